@@ -3,6 +3,8 @@ package citrinelexer
 import (
 	"go/ast"
 	"go/token"
+	"reflect"
+	"strings"
 )
 
 type Node interface {
@@ -20,112 +22,549 @@ type Expression interface {
 	expressionNode()
 }
 
+// Query is a statement that produces rows: a SelectStatement or a
+// CompoundSelect.
+type Query interface {
+	Statement
+	queryNode()
+}
+
 // SELECT statement
 type SelectStatement struct {
-	Select  token.Pos
-	Fields  []Expression
-	From    *TableRef
-	Where   Expression
-	GroupBy []Expression
-	Having  Expression
-	OrderBy []OrderByItem
-	Limit   *LimitClause
-}
-
-func (s *SelectStatement) Pos() token.Pos { return s.Select }
-func (s *SelectStatement) End() token.Pos { return token.NoPos }
+	With     *WithClause
+	Select   token.Pos
+	Distinct bool // SELECT DISTINCT; SELECT ALL is the default
+	Fields   []Expression
+	From     TableExpr
+	Where    Expression
+	GroupBy  []Expression
+	Having   Expression
+	Windows  []*NamedWindow
+	OrderBy  []OrderByItem
+	Limit    *LimitClause
+	End_     token.Pos
+}
+
+func (s *SelectStatement) Pos() token.Pos {
+	if s.With != nil {
+		return s.With.Pos()
+	}
+	return s.Select
+}
+func (s *SelectStatement) End() token.Pos { return s.End_ }
 func (s *SelectStatement) String() string { return "SELECT" }
 func (s *SelectStatement) statementNode() {}
+func (s *SelectStatement) queryNode()     {}
+
+// Compound SELECT: core UNION [ALL] core INTERSECT core ...
+type CompoundSelect struct {
+	With      *WithClause
+	Selects   []*SelectStatement
+	Operators []CompoundOperator // Operators[i] joins Selects[i] and Selects[i+1]
+	OrderBy   []OrderByItem
+	Limit     *LimitClause
+	End_      token.Pos
+}
+
+func (c *CompoundSelect) Pos() token.Pos {
+	if c.With != nil {
+		return c.With.Pos()
+	}
+	return c.Selects[0].Pos()
+}
+func (c *CompoundSelect) End() token.Pos { return c.End_ }
+func (c *CompoundSelect) String() string {
+	out := c.Selects[0].String()
+	for i, op := range c.Operators {
+		out += " " + op.String() + " " + c.Selects[i+1].String()
+	}
+	return out
+}
+func (c *CompoundSelect) statementNode() {}
+func (c *CompoundSelect) queryNode()     {}
+
+type CompoundOperator struct {
+	Type string // "UNION", "INTERSECT" or "EXCEPT"
+	All  bool
+}
+
+func (o CompoundOperator) String() string {
+	if o.All {
+		return o.Type + " ALL"
+	}
+	return o.Type
+}
 
 // CREATE TABLE statement
 type CreateTableStatement struct {
-	Create      token.Pos
-	Table       *Identifier
-	Columns     []*ColumnDef
-	Constraints []Constraint
+	Create       token.Pos
+	Temporary    bool
+	IfNotExists  bool
+	Schema       *Identifier
+	Table        *Identifier
+	Columns      []*ColumnDef
+	Constraints  []Constraint // table constraints
+	WithoutRowID bool
+	AsSelect     Query // CREATE TABLE ... AS SELECT
+	End_         token.Pos
 }
 
 func (c *CreateTableStatement) Pos() token.Pos { return c.Create }
-func (c *CreateTableStatement) End() token.Pos { return token.NoPos }
+func (c *CreateTableStatement) End() token.Pos { return c.End_ }
 func (c *CreateTableStatement) String() string { return "CREATE TABLE" }
 func (c *CreateTableStatement) statementNode() {}
 
+// CREATE [UNIQUE] INDEX statement
+type CreateIndexStatement struct {
+	Create      token.Pos
+	Unique      bool
+	IfNotExists bool
+	Schema      *Identifier
+	Name        *Identifier
+	Table       *Identifier
+	Columns     []OrderByItem // indexed columns, each with an optional direction
+	Where       Expression    // partial index
+	End_        token.Pos
+}
+
+func (c *CreateIndexStatement) Pos() token.Pos { return c.Create }
+func (c *CreateIndexStatement) End() token.Pos { return c.End_ }
+func (c *CreateIndexStatement) String() string { return "CREATE INDEX" }
+func (c *CreateIndexStatement) statementNode() {}
+
+// CREATE VIEW statement
+type CreateViewStatement struct {
+	Create      token.Pos
+	Temporary   bool
+	IfNotExists bool
+	Schema      *Identifier
+	Name        *Identifier
+	Columns     []*Identifier
+	Query       Query
+	End_        token.Pos
+}
+
+func (c *CreateViewStatement) Pos() token.Pos { return c.Create }
+func (c *CreateViewStatement) End() token.Pos { return c.End_ }
+func (c *CreateViewStatement) String() string { return "CREATE VIEW" }
+func (c *CreateViewStatement) statementNode() {}
+
+// CREATE TRIGGER statement
+type CreateTriggerStatement struct {
+	Create      token.Pos
+	Temporary   bool
+	IfNotExists bool
+	Schema      *Identifier
+	Name        *Identifier
+	Time        string // "BEFORE", "AFTER", "INSTEAD OF" or "" when omitted
+	Event       string // "DELETE", "INSERT" or "UPDATE"
+	UpdateOf    []*Identifier
+	Table       *Identifier
+	ForEachRow  bool
+	When        Expression
+	Body        []Statement
+	End_        token.Pos
+}
+
+func (c *CreateTriggerStatement) Pos() token.Pos { return c.Create }
+func (c *CreateTriggerStatement) End() token.Pos { return c.End_ }
+func (c *CreateTriggerStatement) String() string { return "CREATE TRIGGER" }
+func (c *CreateTriggerStatement) statementNode() {}
+
+// DROP TABLE/INDEX/VIEW/TRIGGER statement
+type DropStatement struct {
+	Drop     token.Pos
+	Kind     string // "TABLE", "INDEX", "VIEW" or "TRIGGER"
+	IfExists bool
+	Schema   *Identifier
+	Name     *Identifier
+	End_     token.Pos
+}
+
+func (d *DropStatement) Pos() token.Pos { return d.Drop }
+func (d *DropStatement) End() token.Pos { return d.End_ }
+func (d *DropStatement) String() string { return "DROP " + d.Kind }
+func (d *DropStatement) statementNode() {}
+
+// ALTER TABLE statement
+type AlterTableStatement struct {
+	Alter     token.Pos
+	Schema    *Identifier
+	Table     *Identifier
+	Action    string      // "RENAME TO", "RENAME COLUMN", "ADD COLUMN" or "DROP COLUMN"
+	Column    *Identifier // RENAME COLUMN and DROP COLUMN
+	NewName   *Identifier // RENAME TO and RENAME COLUMN
+	ColumnDef *ColumnDef  // ADD COLUMN
+	End_      token.Pos
+}
+
+func (a *AlterTableStatement) Pos() token.Pos { return a.Alter }
+func (a *AlterTableStatement) End() token.Pos { return a.End_ }
+func (a *AlterTableStatement) String() string { return "ALTER TABLE" }
+func (a *AlterTableStatement) statementNode() {}
+
+// PRAGMA [schema.]name [= value | (value)]
+type PragmaStatement struct {
+	Pragma token.Pos
+	Schema *Identifier
+	Name   *Identifier
+	Value  Expression
+	End_   token.Pos
+}
+
+func (p *PragmaStatement) Pos() token.Pos { return p.Pragma }
+func (p *PragmaStatement) End() token.Pos { return p.End_ }
+func (p *PragmaStatement) String() string { return "PRAGMA" }
+func (p *PragmaStatement) statementNode() {}
+
+// VACUUM [schema] [INTO filename]
+type VacuumStatement struct {
+	Vacuum token.Pos
+	Schema *Identifier
+	Into   Expression
+	End_   token.Pos
+}
+
+func (v *VacuumStatement) Pos() token.Pos { return v.Vacuum }
+func (v *VacuumStatement) End() token.Pos { return v.End_ }
+func (v *VacuumStatement) String() string { return "VACUUM" }
+func (v *VacuumStatement) statementNode() {}
+
+// EXPLAIN [QUERY PLAN] statement
+type ExplainStatement struct {
+	Explain   token.Pos
+	QueryPlan bool
+	Statement Statement
+	End_      token.Pos
+}
+
+func (e *ExplainStatement) Pos() token.Pos { return e.Explain }
+func (e *ExplainStatement) End() token.Pos { return e.End_ }
+func (e *ExplainStatement) String() string {
+	if e.QueryPlan {
+		return "EXPLAIN QUERY PLAN"
+	}
+	return "EXPLAIN"
+}
+func (e *ExplainStatement) statementNode() {}
+
+// ATTACH [DATABASE] expr AS schema
+type AttachStatement struct {
+	Attach   token.Pos
+	Database Expression
+	Schema   *Identifier
+	End_     token.Pos
+}
+
+func (a *AttachStatement) Pos() token.Pos { return a.Attach }
+func (a *AttachStatement) End() token.Pos { return a.End_ }
+func (a *AttachStatement) String() string { return "ATTACH" }
+func (a *AttachStatement) statementNode() {}
+
+// DETACH [DATABASE] schema
+type DetachStatement struct {
+	Detach token.Pos
+	Schema *Identifier
+	End_   token.Pos
+}
+
+func (d *DetachStatement) Pos() token.Pos { return d.Detach }
+func (d *DetachStatement) End() token.Pos { return d.End_ }
+func (d *DetachStatement) String() string { return "DETACH" }
+func (d *DetachStatement) statementNode() {}
+
+// REINDEX [[schema.]name]
+type ReindexStatement struct {
+	Reindex token.Pos
+	Schema  *Identifier
+	Name    *Identifier
+	End_    token.Pos
+}
+
+func (r *ReindexStatement) Pos() token.Pos { return r.Reindex }
+func (r *ReindexStatement) End() token.Pos { return r.End_ }
+func (r *ReindexStatement) String() string { return "REINDEX" }
+func (r *ReindexStatement) statementNode() {}
+
+// ANALYZE [[schema.]name]
+type AnalyzeStatement struct {
+	Analyze token.Pos
+	Schema  *Identifier
+	Name    *Identifier
+	End_    token.Pos
+}
+
+func (a *AnalyzeStatement) Pos() token.Pos { return a.Analyze }
+func (a *AnalyzeStatement) End() token.Pos { return a.End_ }
+func (a *AnalyzeStatement) String() string { return "ANALYZE" }
+func (a *AnalyzeStatement) statementNode() {}
+
+// BEGIN [DEFERRED | IMMEDIATE | EXCLUSIVE] [TRANSACTION]
+type BeginStatement struct {
+	Begin token.Pos
+	Mode  string // "DEFERRED", "IMMEDIATE", "EXCLUSIVE" or "" when omitted
+	End_  token.Pos
+}
+
+func (b *BeginStatement) Pos() token.Pos { return b.Begin }
+func (b *BeginStatement) End() token.Pos { return b.End_ }
+func (b *BeginStatement) String() string { return "BEGIN" }
+func (b *BeginStatement) statementNode() {}
+
+// COMMIT [TRANSACTION] or its synonym END [TRANSACTION]
+type CommitStatement struct {
+	Commit token.Pos
+	End_   token.Pos
+}
+
+func (c *CommitStatement) Pos() token.Pos { return c.Commit }
+func (c *CommitStatement) End() token.Pos { return c.End_ }
+func (c *CommitStatement) String() string { return "COMMIT" }
+func (c *CommitStatement) statementNode() {}
+
+// ROLLBACK [TRANSACTION] [TO [SAVEPOINT] name]
+type RollbackStatement struct {
+	Rollback  token.Pos
+	Savepoint *Identifier // nil for a full rollback
+	End_      token.Pos
+}
+
+func (r *RollbackStatement) Pos() token.Pos { return r.Rollback }
+func (r *RollbackStatement) End() token.Pos { return r.End_ }
+func (r *RollbackStatement) String() string { return "ROLLBACK" }
+func (r *RollbackStatement) statementNode() {}
+
+// SAVEPOINT name
+type SavepointStatement struct {
+	Savepoint token.Pos
+	Name      *Identifier
+	End_      token.Pos
+}
+
+func (s *SavepointStatement) Pos() token.Pos { return s.Savepoint }
+func (s *SavepointStatement) End() token.Pos { return s.End_ }
+func (s *SavepointStatement) String() string { return "SAVEPOINT" }
+func (s *SavepointStatement) statementNode() {}
+
+// RELEASE [SAVEPOINT] name
+type ReleaseStatement struct {
+	Release token.Pos
+	Name    *Identifier
+	End_    token.Pos
+}
+
+func (r *ReleaseStatement) Pos() token.Pos { return r.Release }
+func (r *ReleaseStatement) End() token.Pos { return r.End_ }
+func (r *ReleaseStatement) String() string { return "RELEASE" }
+func (r *ReleaseStatement) statementNode() {}
+
+// BadStatement is a placeholder for a statement that could not be parsed.
+// It is only produced when the parser recovers from errors.
+type BadStatement struct {
+	From token.Pos
+	To   token.Pos
+}
+
+func (b *BadStatement) Pos() token.Pos { return b.From }
+func (b *BadStatement) End() token.Pos { return b.To }
+func (b *BadStatement) String() string { return "BAD STATEMENT" }
+func (b *BadStatement) statementNode() {}
+
 // INSERT statement
 type InsertStatement struct {
-	Insert  token.Pos
-	Table   *Identifier
-	Columns []*Identifier
-	Values  [][]Expression
+	With          *WithClause
+	Insert        token.Pos
+	OnConflict    string // as in UpdateStatement; "REPLACE" for REPLACE INTO
+	Schema        *Identifier
+	Table         *Identifier
+	Columns       []*Identifier
+	Values        [][]Expression
+	Select        Query // INSERT ... SELECT
+	DefaultValues bool  // INSERT ... DEFAULT VALUES
+	End_          token.Pos
 }
 
-func (i *InsertStatement) Pos() token.Pos { return i.Insert }
-func (i *InsertStatement) End() token.Pos { return token.NoPos }
+func (i *InsertStatement) Pos() token.Pos {
+	if i.With != nil {
+		return i.With.Pos()
+	}
+	return i.Insert
+}
+func (i *InsertStatement) End() token.Pos { return i.End_ }
 func (i *InsertStatement) String() string { return "INSERT" }
 func (i *InsertStatement) statementNode() {}
 
 // UPDATE statement
 type UpdateStatement struct {
-	Update token.Pos
-	Table  *Identifier
-	Set    []*Assignment
-	Where  Expression
+	With       *WithClause
+	Update     token.Pos
+	OnConflict string // "ROLLBACK", "ABORT", "REPLACE", "FAIL" or "IGNORE" for UPDATE OR ...
+	Schema     *Identifier
+	Table      *Identifier
+	Set        []*Assignment
+	Where      Expression
+	End_       token.Pos
 }
 
-func (u *UpdateStatement) Pos() token.Pos { return u.Update }
-func (u *UpdateStatement) End() token.Pos { return token.NoPos }
+func (u *UpdateStatement) Pos() token.Pos {
+	if u.With != nil {
+		return u.With.Pos()
+	}
+	return u.Update
+}
+func (u *UpdateStatement) End() token.Pos { return u.End_ }
 func (u *UpdateStatement) String() string { return "UPDATE" }
 func (u *UpdateStatement) statementNode() {}
 
 // DELETE statement
 type DeleteStatement struct {
+	With   *WithClause
 	Delete token.Pos
 	From   *Identifier
 	Where  Expression
+	End_   token.Pos
 }
 
-func (d *DeleteStatement) Pos() token.Pos { return d.Delete }
-func (d *DeleteStatement) End() token.Pos { return token.NoPos }
+func (d *DeleteStatement) Pos() token.Pos {
+	if d.With != nil {
+		return d.With.Pos()
+	}
+	return d.Delete
+}
+func (d *DeleteStatement) End() token.Pos { return d.End_ }
 func (d *DeleteStatement) String() string { return "DELETE" }
 func (d *DeleteStatement) statementNode() {}
 
+// WITH [RECURSIVE] name [(columns)] AS [[NOT] MATERIALIZED] (query), ...
+type WithClause struct {
+	With      token.Pos
+	Recursive bool
+	CTEs      []*CommonTableExpr
+	End_      token.Pos
+}
+
+func (w *WithClause) Pos() token.Pos { return w.With }
+func (w *WithClause) End() token.Pos { return w.End_ }
+func (w *WithClause) String() string {
+	if w.Recursive {
+		return "WITH RECURSIVE"
+	}
+	return "WITH"
+}
+
+type CommonTableExpr struct {
+	Name         *Identifier
+	Columns      []*Identifier
+	Materialized string // "", "MATERIALIZED" or "NOT MATERIALIZED"
+	Query        Query
+	End_         token.Pos
+}
+
+func (c *CommonTableExpr) Pos() token.Pos { return c.Name.Pos() }
+func (c *CommonTableExpr) End() token.Pos { return c.End_ }
+func (c *CommonTableExpr) String() string { return c.Name.String() }
+
 // Expressions
 type Identifier struct {
 	Name string
 	Pos_ token.Pos
+	End_ token.Pos
 }
 
 func (i *Identifier) Pos() token.Pos  { return i.Pos_ }
-func (i *Identifier) End() token.Pos  { return token.NoPos }
+func (i *Identifier) End() token.Pos  { return i.End_ }
 func (i *Identifier) String() string  { return i.Name }
 func (i *Identifier) expressionNode() {}
 
+// BadExpression is a placeholder for an expression that could not be
+// parsed. It is only produced when the parser recovers from errors.
+type BadExpression struct {
+	From token.Pos
+	To   token.Pos
+}
+
+func (b *BadExpression) Pos() token.Pos  { return b.From }
+func (b *BadExpression) End() token.Pos  { return b.To }
+func (b *BadExpression) String() string  { return "BAD EXPRESSION" }
+func (b *BadExpression) expressionNode() {}
+
+// QualifiedIdentifier is a dotted column reference: table.column or
+// schema.table.column. Column is "*" for table.*.
+type QualifiedIdentifier struct {
+	Schema *Identifier
+	Table  *Identifier
+	Column *Identifier
+	End_   token.Pos
+}
+
+func (q *QualifiedIdentifier) Pos() token.Pos {
+	if q.Schema != nil {
+		return q.Schema.Pos()
+	}
+	return q.Table.Pos()
+}
+func (q *QualifiedIdentifier) End() token.Pos { return q.End_ }
+func (q *QualifiedIdentifier) String() string {
+	name := q.Table.String() + "." + q.Column.String()
+	if q.Schema != nil {
+		name = q.Schema.String() + "." + name
+	}
+	return name
+}
+func (q *QualifiedIdentifier) expressionNode() {}
+
 type StringLiteral struct {
 	Value string
 	Pos_  token.Pos
+	End_  token.Pos
 }
 
 func (s *StringLiteral) Pos() token.Pos  { return s.Pos_ }
-func (s *StringLiteral) End() token.Pos  { return token.NoPos }
+func (s *StringLiteral) End() token.Pos  { return s.End_ }
 func (s *StringLiteral) String() string  { return "'" + s.Value + "'" }
 func (s *StringLiteral) expressionNode() {}
 
 type NumberLiteral struct {
 	Value string
 	Pos_  token.Pos
+	End_  token.Pos
 }
 
 func (n *NumberLiteral) Pos() token.Pos  { return n.Pos_ }
-func (n *NumberLiteral) End() token.Pos  { return token.NoPos }
+func (n *NumberLiteral) End() token.Pos  { return n.End_ }
 func (n *NumberLiteral) String() string  { return n.Value }
 func (n *NumberLiteral) expressionNode() {}
 
+// BlobLiteral is X'...'; Value holds the hexadecimal digits.
+type BlobLiteral struct {
+	Value string
+	Pos_  token.Pos
+	End_  token.Pos
+}
+
+func (b *BlobLiteral) Pos() token.Pos  { return b.Pos_ }
+func (b *BlobLiteral) End() token.Pos  { return b.End_ }
+func (b *BlobLiteral) String() string  { return "X'" + b.Value + "'" }
+func (b *BlobLiteral) expressionNode() {}
+
+type NullLiteral struct {
+	Pos_ token.Pos
+	End_ token.Pos
+}
+
+func (n *NullLiteral) Pos() token.Pos  { return n.Pos_ }
+func (n *NullLiteral) End() token.Pos  { return n.End_ }
+func (n *NullLiteral) String() string  { return "NULL" }
+func (n *NullLiteral) expressionNode() {}
+
 type BooleanLiteral struct {
 	Value bool
 	Pos_  token.Pos
+	End_  token.Pos
 }
 
 func (b *BooleanLiteral) Pos() token.Pos { return b.Pos_ }
-func (b *BooleanLiteral) End() token.Pos { return token.NoPos }
+func (b *BooleanLiteral) End() token.Pos { return b.End_ }
 func (b *BooleanLiteral) String() string {
 	if b.Value {
 		return "TRUE"
@@ -138,65 +577,450 @@ type BinaryExpression struct {
 	Left     Expression
 	Operator string
 	Right    Expression
+	Escape   Expression // x LIKE pattern ESCAPE escape
 	Pos_     token.Pos
+	End_     token.Pos
 }
 
 func (b *BinaryExpression) Pos() token.Pos { return b.Pos_ }
-func (b *BinaryExpression) End() token.Pos { return token.NoPos }
+func (b *BinaryExpression) End() token.Pos { return b.End_ }
 func (b *BinaryExpression) String() string {
+	if b.Escape != nil {
+		return b.Left.String() + " " + b.Operator + " " + b.Right.String() + " ESCAPE " + b.Escape.String()
+	}
 	return b.Left.String() + " " + b.Operator + " " + b.Right.String()
 }
 func (b *BinaryExpression) expressionNode() {}
 
-type FunctionCall struct {
-	Name string
-	Args []Expression
+type UnaryExpression struct {
+	Operator string // "-", "+", "~" or "NOT"
+	Operand  Expression
+	Pos_     token.Pos
+	End_     token.Pos
+}
+
+func (u *UnaryExpression) Pos() token.Pos { return u.Pos_ }
+func (u *UnaryExpression) End() token.Pos { return u.End_ }
+func (u *UnaryExpression) String() string {
+	if u.Operator == "NOT" {
+		return "NOT " + u.Operand.String()
+	}
+	return u.Operator + u.Operand.String()
+}
+func (u *UnaryExpression) expressionNode() {}
+
+type ParenExpression struct {
+	Expr Expression
 	Pos_ token.Pos
+	End_ token.Pos
+}
+
+func (p *ParenExpression) Pos() token.Pos  { return p.Pos_ }
+func (p *ParenExpression) End() token.Pos  { return p.End_ }
+func (p *ParenExpression) String() string  { return "(" + p.Expr.String() + ")" }
+func (p *ParenExpression) expressionNode() {}
+
+// expr COLLATE name
+type CollateExpression struct {
+	Expr      Expression
+	Collation string
+	Pos_      token.Pos
+	End_      token.Pos
+}
+
+func (c *CollateExpression) Pos() token.Pos  { return c.Pos_ }
+func (c *CollateExpression) End() token.Pos  { return c.End_ }
+func (c *CollateExpression) String() string  { return c.Expr.String() + " COLLATE " + c.Collation }
+func (c *CollateExpression) expressionNode() {}
+
+// CastExpression is a PostgreSQL cast, expr::type.
+type CastExpression struct {
+	Expr     Expression
+	Type     string
+	TypeArgs []string // "10", "2" in expr::numeric(10,2)
+	Pos_     token.Pos
+	End_     token.Pos
+}
+
+func (c *CastExpression) Pos() token.Pos { return c.Pos_ }
+func (c *CastExpression) End() token.Pos { return c.End_ }
+func (c *CastExpression) String() string {
+	if len(c.TypeArgs) > 0 {
+		return c.Expr.String() + "::" + c.Type + "(" + strings.Join(c.TypeArgs, ", ") + ")"
+	}
+	return c.Expr.String() + "::" + c.Type
+}
+func (c *CastExpression) expressionNode() {}
+
+type BetweenExpression struct {
+	Expr Expression
+	Not  bool
+	Low  Expression
+	High Expression
+	Pos_ token.Pos
+	End_ token.Pos
+}
+
+func (b *BetweenExpression) Pos() token.Pos { return b.Pos_ }
+func (b *BetweenExpression) End() token.Pos { return b.End_ }
+func (b *BetweenExpression) String() string {
+	op := " BETWEEN "
+	if b.Not {
+		op = " NOT BETWEEN "
+	}
+	return b.Expr.String() + op + b.Low.String() + " AND " + b.High.String()
+}
+func (b *BetweenExpression) expressionNode() {}
+
+type InExpression struct {
+	Expr   Expression
+	Not    bool
+	Values []Expression // x IN (1, 2, 3)
+	Query  Query        // x IN (SELECT ...)
+	Pos_   token.Pos
+	End_   token.Pos
+}
+
+func (i *InExpression) Pos() token.Pos { return i.Pos_ }
+func (i *InExpression) End() token.Pos { return i.End_ }
+func (i *InExpression) String() string {
+	op := " IN "
+	if i.Not {
+		op = " NOT IN "
+	}
+	if i.Query != nil {
+		return i.Expr.String() + op + "(" + i.Query.String() + ")"
+	}
+	values := make([]string, len(i.Values))
+	for n, v := range i.Values {
+		values[n] = v.String()
+	}
+	return i.Expr.String() + op + "(" + strings.Join(values, ", ") + ")"
+}
+func (i *InExpression) expressionNode() {}
+
+// Scalar subquery: (SELECT ...)
+type SubqueryExpression struct {
+	Query Query
+	Pos_  token.Pos
+	End_  token.Pos
+}
+
+func (s *SubqueryExpression) Pos() token.Pos  { return s.Pos_ }
+func (s *SubqueryExpression) End() token.Pos  { return s.End_ }
+func (s *SubqueryExpression) String() string  { return "(" + s.Query.String() + ")" }
+func (s *SubqueryExpression) expressionNode() {}
+
+// EXISTS (SELECT ...); NOT EXISTS is a UnaryExpression wrapping it.
+type ExistsExpression struct {
+	Query Query
+	Pos_  token.Pos
+	End_  token.Pos
+}
+
+func (e *ExistsExpression) Pos() token.Pos  { return e.Pos_ }
+func (e *ExistsExpression) End() token.Pos  { return e.End_ }
+func (e *ExistsExpression) String() string  { return "EXISTS (" + e.Query.String() + ")" }
+func (e *ExistsExpression) expressionNode() {}
+
+// CASE [operand] WHEN ... THEN ... [ELSE ...] END. Operand is nil for
+// the searched form.
+type CaseExpression struct {
+	Operand Expression
+	Whens   []*WhenClause
+	Else    Expression
+	Pos_    token.Pos
+	End_    token.Pos
 }
 
-func (f *FunctionCall) Pos() token.Pos  { return f.Pos_ }
-func (f *FunctionCall) End() token.Pos  { return token.NoPos }
-func (f *FunctionCall) String() string  { return f.Name + "()" }
+func (c *CaseExpression) Pos() token.Pos { return c.Pos_ }
+func (c *CaseExpression) End() token.Pos { return c.End_ }
+func (c *CaseExpression) String() string {
+	out := "CASE"
+	if c.Operand != nil {
+		out += " " + c.Operand.String()
+	}
+	for _, when := range c.Whens {
+		out += " WHEN " + when.Condition.String() + " THEN " + when.Result.String()
+	}
+	if c.Else != nil {
+		out += " ELSE " + c.Else.String()
+	}
+	return out + " END"
+}
+func (c *CaseExpression) expressionNode() {}
+
+type WhenClause struct {
+	Condition Expression
+	Result    Expression
+}
+
+// FunctionCall is name(args). COUNT(*) has a single "*" Identifier
+// argument.
+type FunctionCall struct {
+	Name     string
+	Distinct bool
+	Args     []Expression
+	OrderBy  []OrderByItem // group_concat(x ORDER BY y)
+	Filter   Expression    // FILTER (WHERE ...)
+	Over     *WindowSpec   // OVER name or OVER (...)
+	Pos_     token.Pos
+	End_     token.Pos
+}
+
+func (f *FunctionCall) Pos() token.Pos { return f.Pos_ }
+func (f *FunctionCall) End() token.Pos { return f.End_ }
+func (f *FunctionCall) String() string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = arg.String()
+	}
+	if f.Distinct {
+		return f.Name + "(DISTINCT " + strings.Join(args, ", ") + ")"
+	}
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
+}
 func (f *FunctionCall) expressionNode() {}
 
-// Supporting types
+// Table sources
+type TableExpr interface {
+	Node
+	tableExprNode()
+}
+
 type TableRef struct {
-	Name  *Identifier
+	Schema *Identifier // optional, as in main.users
+	Name   *Identifier
+	Alias  *Identifier
+	End_   token.Pos
+}
+
+func (t *TableRef) Pos() token.Pos {
+	if t.Schema != nil {
+		return t.Schema.Pos()
+	}
+	return t.Name.Pos()
+}
+func (t *TableRef) End() token.Pos { return t.End_ }
+func (t *TableRef) String() string {
+	name := t.Name.String()
+	if t.Schema != nil {
+		name = t.Schema.String() + "." + name
+	}
+	if t.Alias != nil {
+		name += " AS " + t.Alias.String()
+	}
+	return name
+}
+func (t *TableRef) tableExprNode() {}
+
+// JoinExpr joins two table sources. Joins are left-deep, so
+// "a JOIN b JOIN c" has "a JOIN b" as the Left of the outer JoinExpr.
+type JoinExpr struct {
+	Left    TableExpr
+	Type    string // ",", "INNER", "LEFT", "RIGHT", "FULL" or "CROSS"
+	Natural bool
+	Right   TableExpr
+	On      Expression
+	Using   []*Identifier
+	End_    token.Pos
+}
+
+func (j *JoinExpr) Pos() token.Pos { return j.Left.Pos() }
+func (j *JoinExpr) End() token.Pos { return j.End_ }
+func (j *JoinExpr) String() string {
+	if j.Type == "," {
+		return j.Left.String() + ", " + j.Right.String()
+	}
+	join := j.Type + " JOIN"
+	if j.Natural {
+		join = "NATURAL " + join
+	}
+	return j.Left.String() + " " + join + " " + j.Right.String()
+}
+func (j *JoinExpr) tableExprNode() {}
+
+// Derived table: (SELECT ...) [AS] alias
+type DerivedTable struct {
+	Query Query
 	Alias *Identifier
+	Pos_  token.Pos
+	End_  token.Pos
+}
+
+func (d *DerivedTable) Pos() token.Pos { return d.Pos_ }
+func (d *DerivedTable) End() token.Pos { return d.End_ }
+func (d *DerivedTable) String() string {
+	out := "(" + d.Query.String() + ")"
+	if d.Alias != nil {
+		out += " AS " + d.Alias.String()
+	}
+	return out
 }
+func (d *DerivedTable) tableExprNode() {}
+
+// Parenthesised join: (a JOIN b)
+type ParenTableExpr struct {
+	Expr TableExpr
+	Pos_ token.Pos
+	End_ token.Pos
+}
+
+func (p *ParenTableExpr) Pos() token.Pos { return p.Pos_ }
+func (p *ParenTableExpr) End() token.Pos { return p.End_ }
+func (p *ParenTableExpr) String() string { return "(" + p.Expr.String() + ")" }
+func (p *ParenTableExpr) tableExprNode() {}
+
+// Supporting types
 
 type ColumnDef struct {
 	Name        *Identifier
 	Type        string
+	TypeArgs    []string // "255" in VARCHAR(255), "10", "2" in DECIMAL(10,2)
 	Constraints []Constraint
 }
 
+// Constraints. Name is set when the constraint is introduced with
+// CONSTRAINT name. Column lists are only set on table constraints.
 type Constraint interface {
 	Node
 	constraintNode()
 }
 
 type PrimaryKeyConstraint struct {
-	Pos_ token.Pos
+	Name          *Identifier
+	Columns       []OrderByItem // table constraint only
+	Order         string        // "ASC" or "DESC"
+	OnConflict    string
+	Autoincrement bool
+	Pos_          token.Pos
+	End_          token.Pos
 }
 
 func (p *PrimaryKeyConstraint) Pos() token.Pos  { return p.Pos_ }
-func (p *PrimaryKeyConstraint) End() token.Pos  { return token.NoPos }
+func (p *PrimaryKeyConstraint) End() token.Pos  { return p.End_ }
 func (p *PrimaryKeyConstraint) String() string  { return "PRIMARY KEY" }
 func (p *PrimaryKeyConstraint) constraintNode() {}
 
 type NotNullConstraint struct {
-	Pos_ token.Pos
+	Name       *Identifier
+	OnConflict string
+	Pos_       token.Pos
+	End_       token.Pos
 }
 
 func (n *NotNullConstraint) Pos() token.Pos  { return n.Pos_ }
-func (n *NotNullConstraint) End() token.Pos  { return token.NoPos }
+func (n *NotNullConstraint) End() token.Pos  { return n.End_ }
 func (n *NotNullConstraint) String() string  { return "NOT NULL" }
 func (n *NotNullConstraint) constraintNode() {}
 
+type UniqueConstraint struct {
+	Name       *Identifier
+	Columns    []OrderByItem // table constraint only
+	OnConflict string
+	Pos_       token.Pos
+	End_       token.Pos
+}
+
+func (u *UniqueConstraint) Pos() token.Pos  { return u.Pos_ }
+func (u *UniqueConstraint) End() token.Pos  { return u.End_ }
+func (u *UniqueConstraint) String() string  { return "UNIQUE" }
+func (u *UniqueConstraint) constraintNode() {}
+
+type CheckConstraint struct {
+	Name *Identifier
+	Expr Expression
+	Pos_ token.Pos
+	End_ token.Pos
+}
+
+func (c *CheckConstraint) Pos() token.Pos  { return c.Pos_ }
+func (c *CheckConstraint) End() token.Pos  { return c.End_ }
+func (c *CheckConstraint) String() string  { return "CHECK (" + c.Expr.String() + ")" }
+func (c *CheckConstraint) constraintNode() {}
+
+type DefaultConstraint struct {
+	Name  *Identifier
+	Value Expression
+	Pos_  token.Pos
+	End_  token.Pos
+}
+
+func (d *DefaultConstraint) Pos() token.Pos  { return d.Pos_ }
+func (d *DefaultConstraint) End() token.Pos  { return d.End_ }
+func (d *DefaultConstraint) String() string  { return "DEFAULT " + d.Value.String() }
+func (d *DefaultConstraint) constraintNode() {}
+
+type CollateConstraint struct {
+	Name      *Identifier
+	Collation string
+	Pos_      token.Pos
+	End_      token.Pos
+}
+
+func (c *CollateConstraint) Pos() token.Pos  { return c.Pos_ }
+func (c *CollateConstraint) End() token.Pos  { return c.End_ }
+func (c *CollateConstraint) String() string  { return "COLLATE " + c.Collation }
+func (c *CollateConstraint) constraintNode() {}
+
+// ForeignKeyConstraint is a column-level REFERENCES clause or a
+// table-level FOREIGN KEY (columns) REFERENCES clause.
+type ForeignKeyConstraint struct {
+	Name       *Identifier
+	Columns    []*Identifier
+	Table      *Identifier
+	RefColumns []*Identifier
+	OnDelete   string // "CASCADE", "RESTRICT", "SET NULL", "SET DEFAULT" or "NO ACTION"
+	OnUpdate   string
+	Deferrable string // "DEFERRABLE" or "NOT DEFERRABLE"
+	Initially  string // "DEFERRED" or "IMMEDIATE"
+	Pos_       token.Pos
+	End_       token.Pos
+}
+
+func (f *ForeignKeyConstraint) Pos() token.Pos  { return f.Pos_ }
+func (f *ForeignKeyConstraint) End() token.Pos  { return f.End_ }
+func (f *ForeignKeyConstraint) String() string  { return "REFERENCES " + f.Table.String() }
+func (f *ForeignKeyConstraint) constraintNode() {}
+
 type Assignment struct {
-	Column *Identifier
-	Value  Expression
+	Column  *Identifier
+	Value   Expression
+	Columns []*Identifier // for row-value assignments: (a, b) = (x, y)
+	Values  []Expression
+}
+
+// WINDOW name AS (spec)
+type NamedWindow struct {
+	Name *Identifier
+	Spec *WindowSpec
+}
+
+// WindowSpec is a window definition. Name is set for "OVER w" and for a
+// definition that extends a named window, as in "OVER (w ORDER BY x)".
+type WindowSpec struct {
+	Name        *Identifier
+	PartitionBy []Expression
+	OrderBy     []OrderByItem
+	Frame       *WindowFrame
+	Pos_        token.Pos
+	End_        token.Pos
+}
+
+func (w *WindowSpec) Pos() token.Pos { return w.Pos_ }
+func (w *WindowSpec) End() token.Pos { return w.End_ }
+func (w *WindowSpec) String() string { return "OVER" }
+
+type WindowFrame struct {
+	Type    string // "ROWS", "RANGE" or "GROUPS"
+	Start   *FrameBound
+	End     *FrameBound // nil unless the frame uses BETWEEN
+	Exclude string      // "", "NO OTHERS", "CURRENT ROW", "GROUP" or "TIES"
+}
+
+type FrameBound struct {
+	Type   string // "UNBOUNDED PRECEDING", "PRECEDING", "CURRENT ROW", "FOLLOWING" or "UNBOUNDED FOLLOWING"
+	Offset Expression
 }
 
 type OrderByItem struct {
@@ -209,14 +1033,18 @@ type LimitClause struct {
 	Offset Expression
 }
 
-// Parameter placeholder
+// Parameter placeholder. Index is the parameter's 1-based number, which
+// follows SQLite's rules: ?NNN has index NNN, a plain ? takes the next
+// number after the largest used so far, and repeated names share a number.
 type Parameter struct {
-	Name string // for named parameters (:name, $name)
-	Pos_ token.Pos
+	Name  string // as written for named and numbered parameters (:name, @name, $name, ?1)
+	Index int
+	Pos_  token.Pos
+	End_  token.Pos
 }
 
 func (p *Parameter) Pos() token.Pos { return p.Pos_ }
-func (p *Parameter) End() token.Pos { return token.NoPos }
+func (p *Parameter) End() token.Pos { return p.End_ }
 func (p *Parameter) String() string {
 	if p.Name == "" {
 		return "?"
@@ -224,3 +1052,97 @@ func (p *Parameter) String() string {
 	return p.Name
 }
 func (p *Parameter) expressionNode() {}
+
+// Comment is a single --, # or /* */ comment, including its markers.
+type Comment struct {
+	Slash token.Pos // position of the leading "--" or "/*"
+	Text  string
+}
+
+func (c *Comment) Pos() token.Pos { return c.Slash }
+func (c *Comment) End() token.Pos { return c.Slash + token.Pos(len(c.Text)) }
+func (c *Comment) String() string { return c.Text }
+
+// CommentGroup is a sequence of comments with no tokens and no empty lines
+// between them.
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) Pos() token.Pos { return g.List[0].Pos() }
+func (g *CommentGroup) End() token.Pos { return g.List[len(g.List)-1].End() }
+func (g *CommentGroup) String() string { return g.Text() }
+
+// Text returns the text of the comments with the comment markers and
+// surrounding blank lines removed, one line per comment line.
+func (g *CommentGroup) Text() string {
+	var lines []string
+	for _, c := range g.List {
+		text := c.Text
+		if strings.HasPrefix(text, "--") {
+			text = text[2:]
+		} else if strings.HasPrefix(text, "#") {
+			text = text[1:]
+		} else {
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		}
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CommentMap maps a node to the comment groups attached to it, in source
+// order. A statement gets the group directly above it and a group on the
+// line where it ends; a group inside a statement is attached to the
+// innermost node that encloses it.
+type CommentMap map[Node][]*CommentGroup
+
+// innermostNode returns the smallest node within n whose range contains
+// pos..end.
+func innermostNode(n Node, pos, end token.Pos) Node {
+	for _, child := range childNodes(n) {
+		if child.Pos() <= pos && end <= child.End() {
+			return innermostNode(child, pos, end)
+		}
+	}
+	return n
+}
+
+// childNodes returns the nodes directly below n, looking through the
+// clauses such as OrderByItem that are not nodes themselves.
+func childNodes(n Node) []Node {
+	var nodes []Node
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			if node, ok := v.Interface().(Node); ok {
+				nodes = append(nodes, node)
+				return
+			}
+			visit(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				visit(v.Field(i))
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				visit(v.Index(i))
+			}
+		}
+	}
+	visit(reflect.Indirect(reflect.ValueOf(n)))
+	return nodes
+}
@@ -0,0 +1,173 @@
+package citrinelexer
+
+import "strings"
+
+// A Dialect describes where an SQL implementation departs from the common
+// syntax: its keywords, how it quotes identifiers and strings, the bind
+// parameters and operators it accepts, and a few grammar extensions.
+//
+// SQLite, PostgreSQL and MySQL are provided. Lexers and parsers use SQLite
+// unless given another dialect with WithLexerDialect or WithDialect. A
+// custom dialect can be made by copying one of them and changing fields.
+type Dialect struct {
+	Name string
+
+	// Keywords maps upper-case words to the keyword tokens they lex as.
+	// Any other word is an IDENTIFIER.
+	Keywords map[string]TokenType
+
+	// IdentifierQuotes and StringQuotes list the characters that open a
+	// quoted identifier and a string literal. '[' is closed by ']'; the
+	// other quotes close themselves and are escaped by doubling.
+	IdentifierQuotes string
+	StringQuotes     string
+
+	// HashComments makes # start a comment that runs to the end of the
+	// line, like --.
+	HashComments bool
+
+	// BackslashEscapes makes a backslash in a string literal start an
+	// escape sequence such as \n or \'.
+	BackslashEscapes bool
+
+	// EscapeStrings enables E'...' strings with C-style escapes, and
+	// DollarQuotes enables $$...$$ and $tag$...$tag$ strings.
+	EscapeStrings bool
+	DollarQuotes  bool
+
+	// Parameters is the set of bind parameter forms the dialect accepts.
+	Parameters ParameterStyle
+
+	// CastOperator enables expr::type casts and JSONOperators the -> and
+	// ->> operators.
+	CastOperator  bool
+	JSONOperators bool
+
+	// HavingWithoutGroupBy allows HAVING in a query without GROUP BY, and
+	// LimitComma allows LIMIT offset, count.
+	HavingWithoutGroupBy bool
+	LimitComma           bool
+}
+
+func (d *Dialect) String() string {
+	return d.Name
+}
+
+// ParameterStyle is a set of bind parameter forms.
+type ParameterStyle uint8
+
+const (
+	QuestionMark   ParameterStyle = 1 << iota // ?
+	QuestionNumber                            // ?NNN
+	ColonName                                 // :name
+	AtName                                    // @name
+	DollarName                                // $name
+	DollarNumber                              // $1
+)
+
+var (
+	SQLite = &Dialect{
+		Name:             "SQLite",
+		Keywords:         keywordSet(sqliteKeywords),
+		IdentifierQuotes: "\"`[",
+		StringQuotes:     "'",
+		Parameters:       QuestionMark | QuestionNumber | ColonName | AtName | DollarName,
+		JSONOperators:    true,
+		LimitComma:       true,
+	}
+
+	PostgreSQL = &Dialect{
+		Name:                 "PostgreSQL",
+		Keywords:             keywordSet(postgresKeywords),
+		IdentifierQuotes:     `"`,
+		StringQuotes:         "'",
+		EscapeStrings:        true,
+		DollarQuotes:         true,
+		Parameters:           DollarNumber,
+		CastOperator:         true,
+		JSONOperators:        true,
+		HavingWithoutGroupBy: true,
+	}
+
+	// MySQL follows the server's default SQL mode, in which double quotes
+	// delimit strings rather than identifiers.
+	MySQL = &Dialect{
+		Name:                 "MySQL",
+		Keywords:             keywordSet(mysqlKeywords),
+		IdentifierQuotes:     "`",
+		StringQuotes:         `'"`,
+		HashComments:         true,
+		BackslashEscapes:     true,
+		Parameters:           QuestionMark,
+		JSONOperators:        true,
+		HavingWithoutGroupBy: true,
+		LimitComma:           true,
+	}
+)
+
+var sqliteKeywords = map[string]TokenType{
+	"AUTOINCREMENT": AUTOINCREMENT,
+	"CONFLICT":      CONFLICT,
+	"FAIL":          FAIL,
+	"ABORT":         ABORT,
+	"ROWID":         ROWID,
+	"PRAGMA":        PRAGMA,
+	"ATTACH":        ATTACH,
+	"DETACH":        DETACH,
+	"QUERY":         QUERY,
+	"PLAN":          PLAN,
+	"GLOB":          GLOB,
+	"MATCH":         MATCH,
+	"REGEXP":        REGEXP,
+	"ISNULL":        ISNULL,
+	"NOTNULL":       NOTNULL,
+	"DEFERRED":      DEFERRED,
+	"IMMEDIATE":     IMMEDIATE,
+	"EXCLUSIVE":     EXCLUSIVE,
+	"DEFERRABLE":    DEFERRABLE,
+	"INITIALLY":     INITIALLY,
+}
+
+var postgresKeywords = map[string]TokenType{
+	"ILIKE":      ILIKE,
+	"ISNULL":     ISNULL,
+	"NOTNULL":    NOTNULL,
+	"DEFERRED":   DEFERRED,
+	"IMMEDIATE":  IMMEDIATE,
+	"DEFERRABLE": DEFERRABLE,
+	"INITIALLY":  INITIALLY,
+}
+
+var mysqlKeywords = map[string]TokenType{
+	"AUTO_INCREMENT": AUTO_INCREMENT,
+	"REGEXP":         REGEXP,
+}
+
+// keywordSet returns the keywords shared by every dialect together with
+// extra.
+func keywordSet(extra map[string]TokenType) map[string]TokenType {
+	set := make(map[string]TokenType, len(keywords)+len(extra))
+	for word, tok := range keywords {
+		set[word] = tok
+	}
+	for word, tok := range extra {
+		set[word] = tok
+	}
+	return set
+}
+
+// lookupIdent returns the keyword token for ident, or IDENTIFIER.
+func (d *Dialect) lookupIdent(ident string) TokenType {
+	if tok, ok := d.Keywords[strings.ToUpper(ident)]; ok {
+		return tok
+	}
+	return IDENTIFIER
+}
+
+func (d *Dialect) identifierQuote(ch rune) bool {
+	return strings.ContainsRune(d.IdentifierQuotes, ch)
+}
+
+func (d *Dialect) stringQuote(ch rune) bool {
+	return strings.ContainsRune(d.StringQuotes, ch)
+}
@@ -0,0 +1,132 @@
+package citrinelexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseError describes a syntax error at a specific token of the input.
+type ParseError struct {
+	Line     int
+	Col      int
+	Offset   int
+	Token    Token
+	Expected []TokenType
+	Msg      string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Col, e.Msg)
+}
+
+// ErrorList is a list of parse errors. The zero value is an empty list
+// ready to use.
+type ErrorList []*ParseError
+
+// Add appends an error to the list.
+func (l *ErrorList) Add(err *ParseError) {
+	*l = append(*l, err)
+}
+
+// Reset empties the list.
+func (l *ErrorList) Reset() {
+	*l = (*l)[0:0]
+}
+
+func (l ErrorList) Len() int      { return len(l) }
+func (l ErrorList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l ErrorList) Less(i, j int) bool {
+	return l[i].Offset < l[j].Offset
+}
+
+// Sort orders the list by source offset.
+func (l ErrorList) Sort() {
+	sort.Sort(l)
+}
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the individual errors so that errors.As can reach each
+// *ParseError in the list.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, err := range l {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns an error equivalent to the list, or nil if the list is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// LexErrorCode classifies a LexError.
+type LexErrorCode int
+
+const (
+	UnterminatedString LexErrorCode = iota + 1
+	UnterminatedIdentifier
+	UnterminatedComment
+	MalformedNumber
+	MalformedBlob
+	MalformedString
+	IllegalCharacter
+	InvalidUTF8
+)
+
+func (c LexErrorCode) String() string {
+	switch c {
+	case UnterminatedString:
+		return "UnterminatedString"
+	case UnterminatedIdentifier:
+		return "UnterminatedIdentifier"
+	case UnterminatedComment:
+		return "UnterminatedComment"
+	case MalformedNumber:
+		return "MalformedNumber"
+	case MalformedBlob:
+		return "MalformedBlob"
+	case MalformedString:
+		return "MalformedString"
+	case IllegalCharacter:
+		return "IllegalCharacter"
+	case InvalidUTF8:
+		return "InvalidUTF8"
+	default:
+		return fmt.Sprintf("LexErrorCode(%d)", int(c))
+	}
+}
+
+// LexError describes malformed input found by the lexer. Except for
+// unterminated comments, the offending text is returned as an ILLEGAL token
+// starting at the same position.
+type LexError struct {
+	Code   LexErrorCode
+	Line   int
+	Col    int
+	Offset int
+	Text   string
+	Msg    string
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Col, e.Msg)
+}
@@ -29,8 +29,8 @@ func ExampleParserUsage() {
 	}
 
 	// Print analysis
-	if selectStmt.From != nil && selectStmt.From.Name != nil {
-		fmt.Printf("Table: %s\n", selectStmt.From.Name.Name)
+	if table, ok := selectStmt.From.(*citrinelexer.TableRef); ok {
+		fmt.Printf("Table: %s\n", table.Name.Name)
 	} else {
 		fmt.Println("Table: <unknown>")
 	}
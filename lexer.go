@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type TokenType int
@@ -149,7 +150,7 @@ const (
 	NUMBER
 	BOOLEAN_LITERAL
 	PARAMETER
-	NAMED_PARAMETER
+	NAMED_PARAMETER // :name, @name, $name
 
 	// Comparison operators
 	EQUAL
@@ -187,6 +188,70 @@ const (
 
 	EOF
 	ILLEGAL
+
+	// Tokens added later are appended here so that the values above do
+	// not change.
+
+	// Statements
+	INTO
+	VALUES
+	SET
+	IF
+	TEMP
+	TEMPORARY
+	VIEW
+	TRIGGER
+	RENAME
+	TO
+	ADD
+	COLUMN
+	BEFORE
+	AFTER
+	INSTEAD
+	OF
+	FOR
+	EACH
+	SAVEPOINT
+	RELEASE
+	DEFERRED
+	IMMEDIATE
+	EXCLUSIVE
+
+	// Foreign key clause
+	ACTION
+	DEFERRABLE
+	INITIALLY
+
+	// Query clauses
+	NATURAL
+	USING
+	ALL
+	WITH
+	RECURSIVE
+	MATERIALIZED
+	GROUPS
+	EXCLUDE
+	NO
+	OTHERS
+	TIES
+	FILTER
+	ILIKE
+	ESCAPE
+
+	// Literals
+	BLOB_LITERAL       // X'53514C'
+	NUMBERED_PARAMETER // ?NNN
+
+	// Operators
+	ARROW       // ->
+	LONG_ARROW  // ->>
+	AMPERSAND   // &
+	TILDE       // ~
+	LEFT_SHIFT  // <<
+	RIGHT_SHIFT // >>
+
+	// Punctuation
+	DOUBLE_COLON // ::
 )
 
 var (
@@ -220,13 +285,17 @@ var singleCharTokens = map[rune]struct {
 	':': {COLON, ":"},
 	'|': {PIPE, "|"},
 	'!': {BANG, "!"},
+	'&': {AMPERSAND, "&"},
+	'~': {TILDE, "~"},
 }
 
 type Token struct {
-	Type  TokenType
-	Value string
-	Line  int
-	Col   int
+	Type      TokenType
+	Value     string
+	Line      int // line of the first character of the token
+	Col       int // column of the first character of the token
+	Offset    int // byte offset of the first character of the token
+	EndOffset int // byte offset just past the last character of the token
 }
 
 func (t Token) String() string {
@@ -247,8 +316,16 @@ func (tt TokenType) String() string {
 		return "UPDATE"
 	case DELETE:
 		return "DELETE"
+	case INTO:
+		return "INTO"
+	case VALUES:
+		return "VALUES"
+	case SET:
+		return "SET"
 	case CREATE:
 		return "CREATE"
+	case TRUNCATE:
+		return "TRUNCATE"
 	case DROP:
 		return "DROP"
 	case ALTER:
@@ -261,6 +338,36 @@ func (tt TokenType) String() string {
 		return "SCHEMA"
 	case INDEX:
 		return "INDEX"
+	case IF:
+		return "IF"
+	case TEMP:
+		return "TEMP"
+	case TEMPORARY:
+		return "TEMPORARY"
+	case VIEW:
+		return "VIEW"
+	case TRIGGER:
+		return "TRIGGER"
+	case RENAME:
+		return "RENAME"
+	case TO:
+		return "TO"
+	case ADD:
+		return "ADD"
+	case COLUMN:
+		return "COLUMN"
+	case BEFORE:
+		return "BEFORE"
+	case AFTER:
+		return "AFTER"
+	case INSTEAD:
+		return "INSTEAD"
+	case OF:
+		return "OF"
+	case FOR:
+		return "FOR"
+	case EACH:
+		return "EACH"
 	case UNIQUE:
 		return "UNIQUE"
 	case PRIMARY:
@@ -275,16 +382,62 @@ func (tt TokenType) String() string {
 		return "CONSTRAINT"
 	case CHECK:
 		return "CHECK"
+	case DEFAULT:
+		return "DEFAULT"
+	case AUTO_INCREMENT:
+		return "AUTO_INCREMENT"
+	case COLLATE:
+		return "COLLATE"
+	case AUTOINCREMENT:
+		return "AUTOINCREMENT"
+	case CASCADE:
+		return "CASCADE"
+	case RESTRICT:
+		return "RESTRICT"
+	case ACTION:
+		return "ACTION"
+	case SET_NULL:
+		return "SET_NULL"
+	case SET_DEFAULT:
+		return "SET_DEFAULT"
+	case WITHOUT:
+		return "WITHOUT"
+	case ROWID:
+		return "ROWID"
 	case NOT:
 		return "NOT"
 	case NULL:
 		return "NULL"
+	case CONFLICT:
+		return "CONFLICT"
+	case ROLLBACK:
+		return "ROLLBACK"
+	case ABORT:
+		return "ABORT"
+	case REPLACE:
+		return "REPLACE"
+	case FAIL:
+		return "FAIL"
+	case IGNORE:
+		return "IGNORE"
 	case PRAGMA:
 		return "PRAGMA"
 	case VACUUM:
 		return "VACUUM"
 	case EXPLAIN:
 		return "EXPLAIN"
+	case REINDEX:
+		return "REINDEX"
+	case ANALYZE:
+		return "ANALYZE"
+	case ATTACH:
+		return "ATTACH"
+	case DETACH:
+		return "DETACH"
+	case QUERY:
+		return "QUERY"
+	case PLAN:
+		return "PLAN"
 	case AND:
 		return "AND"
 	case OR:
@@ -293,12 +446,56 @@ func (tt TokenType) String() string {
 		return "IN"
 	case LIKE:
 		return "LIKE"
+	case ILIKE:
+		return "ILIKE"
+	case GLOB:
+		return "GLOB"
+	case MATCH:
+		return "MATCH"
+	case REGEXP:
+		return "REGEXP"
 	case BETWEEN:
 		return "BETWEEN"
 	case IS:
 		return "IS"
+	case ISNULL:
+		return "ISNULL"
+	case NOTNULL:
+		return "NOTNULL"
 	case EXISTS:
 		return "EXISTS"
+	case OVER:
+		return "OVER"
+	case PARTITION:
+		return "PARTITION"
+	case WINDOW:
+		return "WINDOW"
+	case ROWS:
+		return "ROWS"
+	case RANGE:
+		return "RANGE"
+	case UNBOUNDED:
+		return "UNBOUNDED"
+	case PRECEDING:
+		return "PRECEDING"
+	case FOLLOWING:
+		return "FOLLOWING"
+	case CURRENT:
+		return "CURRENT"
+	case ROW:
+		return "ROW"
+	case GROUPS:
+		return "GROUPS"
+	case EXCLUDE:
+		return "EXCLUDE"
+	case NO:
+		return "NO"
+	case OTHERS:
+		return "OTHERS"
+	case TIES:
+		return "TIES"
+	case FILTER:
+		return "FILTER"
 	case CASE:
 		return "CASE"
 	case WHEN:
@@ -325,10 +522,18 @@ func (tt TokenType) String() string {
 		return "DISTINCT"
 	case UNION:
 		return "UNION"
+	case ALL:
+		return "ALL"
 	case INTERSECT:
 		return "INTERSECT"
 	case EXCEPT:
 		return "EXCEPT"
+	case WITH:
+		return "WITH"
+	case RECURSIVE:
+		return "RECURSIVE"
+	case MATERIALIZED:
+		return "MATERIALIZED"
 	case JOIN:
 		return "JOIN"
 	case INNER:
@@ -337,6 +542,16 @@ func (tt TokenType) String() string {
 		return "LEFT"
 	case RIGHT:
 		return "RIGHT"
+	case FULL:
+		return "FULL"
+	case OUTER:
+		return "OUTER"
+	case CROSS:
+		return "CROSS"
+	case NATURAL:
+		return "NATURAL"
+	case USING:
+		return "USING"
 	case ON:
 		return "ON"
 	case AS:
@@ -351,6 +566,8 @@ func (tt TokenType) String() string {
 		return "MAX"
 	case MIN:
 		return "MIN"
+	case INT:
+		return "INT"
 	case INTEGER:
 		return "INTEGER"
 	case TEXT:
@@ -369,6 +586,22 @@ func (tt TokenType) String() string {
 		return "DATETIME"
 	case TIMESTAMP:
 		return "TIMESTAMP"
+	case BEGIN:
+		return "BEGIN"
+	case COMMIT:
+		return "COMMIT"
+	case TRANSACTION:
+		return "TRANSACTION"
+	case DEFERRED:
+		return "DEFERRED"
+	case IMMEDIATE:
+		return "IMMEDIATE"
+	case EXCLUSIVE:
+		return "EXCLUSIVE"
+	case SAVEPOINT:
+		return "SAVEPOINT"
+	case RELEASE:
+		return "RELEASE"
 	case TRUE:
 		return "TRUE"
 	case FALSE:
@@ -379,10 +612,16 @@ func (tt TokenType) String() string {
 		return "STRING"
 	case NUMBER:
 		return "NUMBER"
+	case BOOLEAN_LITERAL:
+		return "BOOLEAN_LITERAL"
+	case BLOB_LITERAL:
+		return "BLOB_LITERAL"
 	case PARAMETER:
 		return "PARAMETER"
 	case NAMED_PARAMETER:
 		return "NAMED_PARAMETER"
+	case NUMBERED_PARAMETER:
+		return "NUMBERED_PARAMETER"
 	case EQUAL:
 		return "EQUAL"
 	case NOT_EQUAL:
@@ -409,6 +648,10 @@ func (tt TokenType) String() string {
 		return "MODULO"
 	case CONCAT:
 		return "CONCAT"
+	case ARROW:
+		return "ARROW"
+	case LONG_ARROW:
+		return "LONG_ARROW"
 	case SEMICOLON:
 		return "SEMICOLON"
 	case COMMA:
@@ -427,14 +670,34 @@ func (tt TokenType) String() string {
 		return "RBRACKET"
 	case COLON:
 		return "COLON"
+	case DOUBLE_COLON:
+		return "DOUBLE_COLON"
 	case PIPE:
 		return "PIPE"
 	case BANG:
 		return "BANG"
+	case LINE_COMMENT:
+		return "LINE_COMMENT"
+	case BLOCK_COMMENT:
+		return "BLOCK_COMMENT"
 	case EOF:
 		return "EOF"
 	case ILLEGAL:
 		return "ILLEGAL"
+	case DEFERRABLE:
+		return "DEFERRABLE"
+	case INITIALLY:
+		return "INITIALLY"
+	case AMPERSAND:
+		return "AMPERSAND"
+	case TILDE:
+		return "TILDE"
+	case LEFT_SHIFT:
+		return "LEFT_SHIFT"
+	case RIGHT_SHIFT:
+		return "RIGHT_SHIFT"
+	case ESCAPE:
+		return "ESCAPE"
 	default:
 		return fmt.Sprintf("TokenType(%d)", int(tt))
 	}
@@ -447,13 +710,45 @@ type Lexer struct {
 	ch       rune
 	line     int
 	col      int
+
+	comments bool
+	dialect  *Dialect
+
+	// errors holds the diagnostics reported so far. illegal and illegalMsg
+	// describe what is wrong with the token being read, if anything.
+	errors     []*LexError
+	illegal    LexErrorCode
+	illegalMsg string
+}
+
+// LexerOption configures a Lexer created by NewLexer.
+type LexerOption func(*Lexer)
+
+// WithComments makes the lexer return comments as LINE_COMMENT and
+// BLOCK_COMMENT tokens, including their markers, instead of skipping them.
+func WithComments() LexerOption {
+	return func(l *Lexer) {
+		l.comments = true
+	}
 }
 
-func NewLexer(input string) *Lexer {
+// WithLexerDialect makes the lexer read input as written for d. The
+// default is SQLite.
+func WithLexerDialect(d *Dialect) LexerOption {
+	return func(l *Lexer) {
+		l.dialect = d
+	}
+}
+
+func NewLexer(input string, opts ...LexerOption) *Lexer {
 	l := &Lexer{
-		input: input,
-		line:  1,
-		col:   0,
+		input:   input,
+		line:    1,
+		col:     0,
+		dialect: SQLite,
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
 	l.readChar()
 	return l
@@ -462,13 +757,23 @@ func NewLexer(input string) *Lexer {
 func (l *Lexer) readChar() {
 	l.position = l.readPos
 	if l.readPos >= len(l.input) {
+		if l.readPos == len(l.input) {
+			// EOF sits one column past the last character.
+			l.col++
+		}
 		l.ch = 0
 		l.readPos++
 		return
 	}
 
-	l.ch = rune(l.input[l.readPos])
-	l.readPos++
+	// Decode multi-byte characters; ASCII takes the fast path. Columns
+	// count characters while position and readPos stay byte offsets.
+	r, width := rune(l.input[l.readPos]), 1
+	if r >= utf8.RuneSelf {
+		r, width = utf8.DecodeRuneInString(l.input[l.readPos:])
+	}
+	l.ch = r
+	l.readPos += width
 
 	if l.ch == '\n' {
 		l.line++
@@ -482,7 +787,11 @@ func (l *Lexer) peekChar() rune {
 	if l.readPos >= len(l.input) {
 		return 0
 	}
-	return rune(l.input[l.readPos])
+	r := rune(l.input[l.readPos])
+	if r >= utf8.RuneSelf {
+		r, _ = utf8.DecodeRuneInString(l.input[l.readPos:])
+	}
+	return r
 }
 
 func (l *Lexer) IsAtEnd() bool {
@@ -493,160 +802,291 @@ func (l *Lexer) GetCurrentPosition() (int, int) {
 	return l.line, l.col
 }
 
+// MakeToken returns a token of the given type and value positioned at the
+// lexer's current position, which is past the end of the token just read.
+//
+// Deprecated: NextToken sets Line, Col, Offset and EndOffset from where
+// each token starts and ends; build tokens with a Token literal instead.
 func (l *Lexer) MakeToken(tokenType TokenType, value string) Token {
-	return Token{
-		Type:  tokenType,
-		Value: value,
-		Line:  l.line,
-		Col:   l.col,
-	}
+	offset := l.offset()
+	return Token{Type: tokenType, Value: value, Line: l.line, Col: l.col, Offset: offset, EndOffset: offset}
 }
 
 func (l *Lexer) NextToken() Token {
-	var tok Token
+	for {
+		l.skipWhitespace()
+		if l.atLineComment() && !l.comments {
+			l.skipLineComment()
+			continue
+		}
+		// An unterminated block comment is left for readToken, which
+		// returns it as ILLEGAL.
+		if l.ch == '/' && l.peekChar() == '*' && !l.comments &&
+			strings.Contains(l.input[l.readPos+1:], "*/") {
+			l.skipBlockComment()
+			continue
+		}
+		break
+	}
+
+	line, col, start := l.line, l.col, l.offset()
+	l.illegal = 0
+	tok := l.readToken()
+	tok.Line = line
+	tok.Col = col
+	tok.Offset = start
+	tok.EndOffset = l.offset()
+
+	// A token flagged while it was read, one with invalid UTF-8 anywhere
+	// (even inside a quoted string) and a stray character are all returned
+	// as ILLEGAL and reported.
+	text := l.input[tok.Offset:tok.EndOffset]
+	switch {
+	case l.illegal != 0:
+	case !utf8.ValidString(text):
+		l.markIllegal(InvalidUTF8, "invalid UTF-8 encoding")
+	case tok.Type == ILLEGAL:
+		l.markIllegal(IllegalCharacter, fmt.Sprintf("illegal character %q", text))
+	}
 
-	l.skipWhitespace()
+	if l.illegal != 0 {
+		tok.Type = ILLEGAL
+		tok.Value = text
+		l.addError(l.illegal, line, col, start, text, l.illegalMsg)
+	}
+	return tok
+}
+
+// offset returns the byte offset of the current character, clamped to the
+// input length once the lexer has reached EOF.
+func (l *Lexer) offset() int {
+	if l.position > len(l.input) {
+		return len(l.input)
+	}
+	return l.position
+}
+
+// Errors returns the diagnostics for malformed input found so far.
+func (l *Lexer) Errors() []*LexError {
+	return l.errors
+}
+
+// markIllegal flags the token being read as ILLEGAL for the given reason.
+func (l *Lexer) markIllegal(code LexErrorCode, msg string) {
+	l.illegal = code
+	l.illegalMsg = msg
+}
+
+func (l *Lexer) addError(code LexErrorCode, line, col, offset int, text, msg string) {
+	l.errors = append(l.errors, &LexError{
+		Code:   code,
+		Line:   line,
+		Col:    col,
+		Offset: offset,
+		Text:   text,
+		Msg:    msg,
+	})
+}
+
+func (l *Lexer) readToken() Token {
+	var tok Token
 
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
 			l.readChar()
-			tok = l.MakeToken(EQUAL, doubleEqualStr)
-			tok.Col = l.col - 1
+			tok = Token{Type: EQUAL, Value: doubleEqualStr}
 		} else {
-			tok = l.MakeToken(EQUAL, equalStr)
+			tok = Token{Type: EQUAL, Value: equalStr}
 		}
 	case '>':
 		if l.peekChar() == '=' {
 			l.readChar()
-			tok = Token{Type: GREATER_EQUAL, Value: greaterEqualStr, Line: l.line, Col: l.col - 1}
+			tok = Token{Type: GREATER_EQUAL, Value: greaterEqualStr}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: RIGHT_SHIFT, Value: ">>"}
 		} else {
-			tok = Token{Type: GREATER, Value: greaterStr, Line: l.line, Col: l.col}
+			tok = Token{Type: GREATER, Value: greaterStr}
 		}
 	case '<':
 		if l.peekChar() == '=' {
 			l.readChar()
-			tok = Token{Type: LESS_EQUAL, Value: lessEqualStr, Line: l.line, Col: l.col - 1}
+			tok = Token{Type: LESS_EQUAL, Value: lessEqualStr}
 		} else if l.peekChar() == '>' {
 			l.readChar()
-			tok = Token{Type: NOT_EQUAL2, Value: notEqualStr2, Line: l.line, Col: l.col - 1}
+			tok = Token{Type: NOT_EQUAL2, Value: notEqualStr2}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = Token{Type: LEFT_SHIFT, Value: "<<"}
 		} else {
-			tok = Token{Type: LESS, Value: lessStr, Line: l.line, Col: l.col}
+			tok = Token{Type: LESS, Value: lessStr}
 		}
 	case '!':
 		if l.peekChar() == '=' {
 			l.readChar()
-			tok = Token{Type: NOT_EQUAL, Value: notEqualStr1, Line: l.line, Col: l.col - 1}
+			tok = Token{Type: NOT_EQUAL, Value: notEqualStr1}
 		} else {
-			tok = Token{Type: BANG, Value: string(l.ch), Line: l.line, Col: l.col}
+			tok = Token{Type: BANG, Value: string(l.ch)}
 		}
 	case '|':
 		if l.peekChar() == '|' {
 			l.readChar()
-			tok = Token{Type: CONCAT, Value: "||", Line: l.line, Col: l.col - 1}
+			tok = Token{Type: CONCAT, Value: "||"}
 		} else {
-			tok = Token{Type: PIPE, Value: "|", Line: l.line, Col: l.col}
+			tok = Token{Type: PIPE, Value: "|"}
 		}
 	case '-':
 		if l.peekChar() == '-' {
+			position := l.position
 			l.skipLineComment()
-			return l.NextToken()
+			return Token{Type: LINE_COMMENT, Value: l.input[position:l.offset()]}
+		}
+		if l.peekChar() == '>' && l.dialect.JSONOperators {
+			l.readChar()
+			if l.peekChar() == '>' {
+				l.readChar()
+				tok = Token{Type: LONG_ARROW, Value: "->>"}
+			} else {
+				tok = Token{Type: ARROW, Value: "->"}
+			}
+			break
 		}
 		if charToken, ok := singleCharTokens[l.ch]; ok {
-			tok = Token{Type: charToken.TokenType, Value: charToken.Value, Line: l.line, Col: l.col}
+			tok = Token{Type: charToken.TokenType, Value: charToken.Value}
 		} else {
-			tok = Token{Type: ILLEGAL, Value: string(l.ch), Line: l.line, Col: l.col}
+			tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 		}
+	case '#':
+		if l.dialect.HashComments {
+			position := l.position
+			l.skipLineComment()
+			return Token{Type: LINE_COMMENT, Value: l.input[position:l.offset()]}
+		}
+		tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 	case '/':
 		if l.peekChar() == '*' {
+			position := l.position
 			l.skipBlockComment()
-			return l.NextToken()
+			return Token{Type: BLOCK_COMMENT, Value: l.input[position:l.offset()]}
 		}
 		if charToken, ok := singleCharTokens[l.ch]; ok {
-			tok = Token{Type: charToken.TokenType, Value: charToken.Value, Line: l.line, Col: l.col}
+			tok = Token{Type: charToken.TokenType, Value: charToken.Value}
 		} else {
-			tok = Token{Type: ILLEGAL, Value: string(l.ch), Line: l.line, Col: l.col}
+			tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 		}
-	case ';', ',', '(', ')', '*', '+', '%', ']':
+	case ';', ',', '(', ')', '*', '+', '%', ']', '&', '~':
 		if charToken, ok := singleCharTokens[l.ch]; ok {
-			tok = Token{Type: charToken.TokenType, Value: charToken.Value, Line: l.line, Col: l.col}
+			tok = Token{Type: charToken.TokenType, Value: charToken.Value}
 		} else {
-			tok = Token{Type: ILLEGAL, Value: string(l.ch), Line: l.line, Col: l.col}
+			tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 		}
 	case '.':
 		if isDigit(l.peekChar()) {
 			tok.Type = NUMBER
 			tok.Value = l.readNumber()
-			tok.Line = l.line
-			tok.Col = l.col
 			return tok
 		}
-		tok = Token{Type: DOT, Value: ".", Line: l.line, Col: l.col}
-	case '\'':
-		tok.Type = STRING
-		tok.Value = l.readString('\'')
-		tok.Line = l.line
-		tok.Col = l.col
-		return tok
-	case '"':
-		tok.Type = IDENTIFIER
-		tok.Value = l.readString('"')
-		tok.Line = l.line
-		tok.Col = l.col
-		return tok
-	case '`':
-		tok.Type = IDENTIFIER
-		tok.Value = l.readString('`')
-		tok.Line = l.line
-		tok.Col = l.col
-		return tok
+		tok = Token{Type: DOT, Value: "."}
+	case '\'', '"', '`':
+		if l.dialect.stringQuote(l.ch) {
+			tok.Type = STRING
+			tok.Value = l.readString(l.ch, false)
+			return tok
+		}
+		if l.dialect.identifierQuote(l.ch) {
+			tok.Type = IDENTIFIER
+			tok.Value = l.readString(l.ch, true)
+			return tok
+		}
+		tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 	case '[':
-		tok.Type = IDENTIFIER
-		tok.Value = l.readBracketIdentifier()
-		tok.Line = l.line
-		tok.Col = l.col
-		return tok
+		if l.dialect.identifierQuote('[') {
+			tok.Type = IDENTIFIER
+			tok.Value = l.readBracketIdentifier()
+			return tok
+		}
+		tok = Token{Type: LBRACKET, Value: "["}
 	case '?':
-		tok = Token{Type: PARAMETER, Value: "?", Line: l.line, Col: l.col}
+		if l.dialect.Parameters&QuestionNumber != 0 && isDigit(l.peekChar()) {
+			position := l.position
+			l.readChar() // skip ?
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+			return Token{Type: NUMBERED_PARAMETER, Value: l.input[position:l.position]}
+		}
+		if l.dialect.Parameters&QuestionMark != 0 {
+			tok = Token{Type: PARAMETER, Value: "?"}
+		} else {
+			tok = Token{Type: ILLEGAL, Value: string(l.ch)}
+		}
+	case '@':
+		if l.dialect.Parameters&AtName != 0 && isLetter(l.peekChar()) {
+			tok.Type = NAMED_PARAMETER
+			tok.Value = l.readNamedParameter()
+			return tok
+		}
+		tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 	case ':':
-		if isLetter(l.peekChar()) {
+		if l.peekChar() == ':' && l.dialect.CastOperator {
+			l.readChar()
+			tok = Token{Type: DOUBLE_COLON, Value: "::"}
+			break
+		}
+		if l.dialect.Parameters&ColonName != 0 && isLetter(l.peekChar()) {
 			tok.Type = NAMED_PARAMETER
 			tok.Value = l.readNamedParameter()
-			tok.Line = l.line
-			tok.Col = l.col
 			return tok
 		}
 		if charToken, ok := singleCharTokens[l.ch]; ok {
-			tok = Token{Type: charToken.TokenType, Value: charToken.Value, Line: l.line, Col: l.col}
+			tok = Token{Type: charToken.TokenType, Value: charToken.Value}
 		} else {
-			tok = Token{Type: ILLEGAL, Value: string(l.ch), Line: l.line, Col: l.col}
+			tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 		}
 	case '$':
-		if isLetter(l.peekChar()) || isDigit(l.peekChar()) {
+		if tag, ok := l.dollarQuoteTag(); ok && l.dialect.DollarQuotes {
+			tok.Type = STRING
+			tok.Value = l.readDollarQuoted(tag)
+			return tok
+		}
+		if l.dialect.Parameters&DollarNumber != 0 && isDigit(l.peekChar()) {
+			position := l.position
+			l.readChar() // skip $
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+			return Token{Type: NUMBERED_PARAMETER, Value: l.input[position:l.position]}
+		}
+		if l.dialect.Parameters&DollarName != 0 && (isLetter(l.peekChar()) || isDigit(l.peekChar())) {
 			tok.Type = NAMED_PARAMETER
 			tok.Value = l.readNamedParameter()
-			tok.Line = l.line
-			tok.Col = l.col
 			return tok
 		}
-		tok = Token{Type: ILLEGAL, Value: string(l.ch), Line: l.line, Col: l.col}
+		tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 	case 0:
-		tok = Token{Type: EOF, Value: emptyStr, Line: l.line, Col: l.col}
+		tok = Token{Type: EOF, Value: emptyStr}
 	default:
+		if (l.ch == 'x' || l.ch == 'X') && l.peekChar() == '\'' {
+			tok.Type = BLOB_LITERAL
+			tok.Value = l.readBlob()
+			return tok
+		}
+		if (l.ch == 'e' || l.ch == 'E') && l.peekChar() == '\'' && l.dialect.EscapeStrings {
+			tok.Type = STRING
+			tok.Value = l.readEscapeString()
+			return tok
+		}
 		if isLetter(l.ch) {
 			tok.Value = l.readIdentifier()
-			tok.Type = lookupIdent(tok.Value)
-			tok.Line = l.line
-			tok.Col = l.col
+			tok.Type = l.dialect.lookupIdent(tok.Value)
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = NUMBER
 			tok.Value = l.readNumber()
-			tok.Line = l.line
-			tok.Col = l.col
 			return tok
 		} else {
-			tok = Token{Type: ILLEGAL, Value: string(l.ch), Line: l.line, Col: l.col}
+			tok = Token{Type: ILLEGAL, Value: string(l.ch)}
 		}
 	}
 
@@ -681,23 +1121,20 @@ func (l *Lexer) readNumber() string {
 	if l.ch == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X') {
 		l.readChar() // skip 0
 		l.readChar() // skip x
-		for isHexDigit(l.ch) {
-			l.readChar()
+		if !isHexDigit(l.ch) {
+			l.markIllegal(MalformedNumber, "hexadecimal literal has no digits")
 		}
+		l.readDigits(isHexDigit)
 		return l.input[position:l.position]
 	}
 
 	// Regular numbers
-	for isDigit(l.ch) {
-		l.readChar()
-	}
+	l.readDigits(isDigit)
 
 	// Handle decimal point
 	if l.ch == '.' && isDigit(l.peekChar()) {
 		l.readChar()
-		for isDigit(l.ch) {
-			l.readChar()
-		}
+		l.readDigits(isDigit)
 	}
 
 	// Handle scientific notation
@@ -706,20 +1143,206 @@ func (l *Lexer) readNumber() string {
 		if l.ch == '+' || l.ch == '-' {
 			l.readChar()
 		}
-		for isDigit(l.ch) {
-			l.readChar()
+		if !isDigit(l.ch) {
+			l.markIllegal(MalformedNumber, "exponent has no digits")
 		}
+		l.readDigits(isDigit)
 	}
 
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString(delimiter rune) string {
+// readDigits reads a run of digits, allowing single underscores between
+// them as separators, as in 1_000_000.
+func (l *Lexer) readDigits(valid func(rune) bool) {
+	for valid(l.ch) || l.ch == '_' {
+		if l.ch == '_' && !valid(l.peekChar()) {
+			l.markIllegal(MalformedNumber, "digit separator must be between digits")
+		}
+		l.readChar()
+	}
+}
+
+// readBlob reads a blob literal X'hex' and returns its hex digits.
+func (l *Lexer) readBlob() string {
+	l.readChar() // skip X
+	l.readChar() // skip '
+
+	position := l.position
+	for l.ch != '\'' && l.ch != 0 {
+		if !isHexDigit(l.ch) {
+			l.markIllegal(MalformedBlob, "blob literal contains a non-hexadecimal character")
+		}
+		l.readChar()
+	}
+	value := l.input[position:l.position]
+
+	if l.ch == 0 {
+		l.markIllegal(UnterminatedString, "unterminated blob literal")
+		return value
+	}
+	l.readChar() // skip '
+
+	if len(value)%2 != 0 && l.illegal == 0 {
+		l.markIllegal(MalformedBlob, "blob literal has an odd number of hexadecimal digits")
+	}
+	return value
+}
+
+// readEscapeString reads a PostgreSQL escape string E'...', in which a
+// backslash starts a C-style escape sequence.
+func (l *Lexer) readEscapeString() string {
+	var result strings.Builder
+	l.readChar() // skip E
+	l.readChar() // skip '
+
+	for {
+		switch l.ch {
+		case 0:
+			l.markIllegal(UnterminatedString, "unterminated string literal")
+			return result.String()
+		case '\'':
+			l.readChar()
+			if l.ch != '\'' {
+				return result.String()
+			}
+			result.WriteRune('\'')
+			l.readChar()
+		case '\\':
+			l.readChar()
+			l.readEscape(&result)
+		default:
+			result.WriteRune(l.ch)
+			l.readChar()
+		}
+	}
+}
+
+// simpleEscapes maps the letter after a backslash in an escape string to
+// the character it stands for.
+var simpleEscapes = map[rune]rune{
+	'b': '\b',
+	'f': '\f',
+	'n': '\n',
+	'r': '\r',
+	't': '\t',
+}
+
+// readEscape reads the escape sequence after a backslash in an escape
+// string and writes the character it stands for.
+func (l *Lexer) readEscape(result *strings.Builder) {
+	if r, ok := simpleEscapes[l.ch]; ok {
+		result.WriteRune(r)
+		l.readChar()
+		return
+	}
+
+	base, max := 0, 0
+	switch {
+	case l.ch >= '0' && l.ch <= '7':
+		base, max = 8, 3
+	case l.ch == 'x':
+		base, max = 16, 2
+	case l.ch == 'u':
+		base, max = 16, 4
+	case l.ch == 'U':
+		base, max = 16, 8
+	case l.ch == 0:
+		return
+	default:
+		// Any other character stands for itself, as in \' or \\.
+		result.WriteRune(l.ch)
+		l.readChar()
+		return
+	}
+	if base == 16 {
+		l.readChar() // skip x, u or U
+	}
+
+	value, n := 0, 0
+	for ; n < max; n++ {
+		digit, ok := digitValue(l.ch)
+		if !ok || digit >= base {
+			break
+		}
+		value = value*base + digit
+		l.readChar()
+	}
+	if n == 0 {
+		l.markIllegal(MalformedString, "escape sequence has no digits")
+		return
+	}
+	result.WriteRune(rune(value))
+}
+
+func digitValue(ch rune) (int, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0'), true
+	case ch >= 'a' && ch <= 'f':
+		return int(ch-'a') + 10, true
+	case ch >= 'A' && ch <= 'F':
+		return int(ch-'A') + 10, true
+	default:
+		return 0, false
+	}
+}
+
+// dollarQuoteTag reports whether the lexer is at the opening delimiter of
+// a dollar-quoted string, $$ or $tag$, and returns the delimiter.
+func (l *Lexer) dollarQuoteTag() (string, bool) {
+	end := l.position + 1
+	for end < len(l.input) {
+		ch := rune(l.input[end])
+		if ch == '$' {
+			return l.input[l.position : end+1], true
+		}
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' ||
+			end > l.position+1 && ch >= '0' && ch <= '9') {
+			return "", false
+		}
+		end++
+	}
+	return "", false
+}
+
+// readDollarQuoted reads a dollar-quoted string whose opening delimiter is
+// tag and returns its contents verbatim.
+func (l *Lexer) readDollarQuoted(tag string) string {
+	for range tag {
+		l.readChar()
+	}
+
+	position := l.position
+	end := strings.Index(l.input[position:], tag)
+	if end < 0 {
+		l.markIllegal(UnterminatedString, "unterminated dollar-quoted string")
+		for l.ch != 0 {
+			l.readChar()
+		}
+		return l.input[position:]
+	}
+
+	for l.position < position+end+len(tag) {
+		l.readChar()
+	}
+	return l.input[position : position+end]
+}
+
+// readString reads a string literal or, if identifier is set, a quoted
+// identifier. A doubled delimiter stands for itself, and in dialects with
+// backslash escapes a backslash in a string literal starts an escape.
+func (l *Lexer) readString(delimiter rune, identifier bool) string {
 	var result strings.Builder
 	l.readChar()
 
 	for {
 		if l.ch == 0 {
+			if identifier {
+				l.markIllegal(UnterminatedIdentifier, "unterminated quoted identifier")
+			} else {
+				l.markIllegal(UnterminatedString, "unterminated string literal")
+			}
 			break
 		}
 
@@ -734,10 +1357,9 @@ func (l *Lexer) readString(delimiter rune) string {
 			break
 		}
 
-		if l.ch == '\\' && l.peekChar() == delimiter {
-			l.readChar()
-			result.WriteRune(l.ch)
+		if l.ch == '\\' && !identifier && l.dialect.BackslashEscapes {
 			l.readChar()
+			l.readBackslashEscape(&result)
 			continue
 		}
 
@@ -748,6 +1370,37 @@ func (l *Lexer) readString(delimiter rune) string {
 	return result.String()
 }
 
+// backslashEscapes maps the character after a backslash in a MySQL string
+// to the character it stands for. Any other character stands for itself.
+var backslashEscapes = map[rune]rune{
+	'0': 0,
+	'b': '\b',
+	'n': '\n',
+	'r': '\r',
+	't': '\t',
+	'Z': '\x1a',
+}
+
+// readBackslashEscape reads the character after a backslash in a string
+// literal of a dialect with backslash escapes.
+func (l *Lexer) readBackslashEscape(result *strings.Builder) {
+	switch l.ch {
+	case 0:
+		return
+	case '%', '_':
+		// Kept escaped so that LIKE matches them literally.
+		result.WriteRune('\\')
+		result.WriteRune(l.ch)
+	default:
+		if r, ok := backslashEscapes[l.ch]; ok {
+			result.WriteRune(r)
+		} else {
+			result.WriteRune(l.ch)
+		}
+	}
+	l.readChar()
+}
+
 func isLetter(ch rune) bool {
 	if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_' {
 		return true
@@ -766,7 +1419,13 @@ func isHexDigit(ch rune) bool {
 	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
 }
 
-// skipLineComment skips -- style comments
+// atLineComment reports whether a -- comment, or a # comment in a dialect
+// with HashComments, starts at the current character.
+func (l *Lexer) atLineComment() bool {
+	return l.ch == '-' && l.peekChar() == '-' || l.ch == '#' && l.dialect.HashComments
+}
+
+// skipLineComment skips -- and # style comments
 func (l *Lexer) skipLineComment() {
 	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
@@ -780,6 +1439,7 @@ func (l *Lexer) skipBlockComment() {
 
 	for {
 		if l.ch == 0 {
+			l.markIllegal(UnterminatedComment, "unterminated block comment")
 			break
 		}
 		if l.ch == '*' && l.peekChar() == '/' {
@@ -814,63 +1474,75 @@ func (l *Lexer) readBracketIdentifier() string {
 	value := l.input[position+1 : l.position] // exclude brackets
 	if l.ch == ']' {
 		l.readChar()
+	} else {
+		l.markIllegal(UnterminatedIdentifier, "unterminated quoted identifier")
 	}
 	return value
 }
 
+// keywords are the keywords shared by every dialect. Dialect-specific ones
+// are in dialect.go.
 var keywords = map[string]TokenType{
 	// Basic SQL statements
-	"SELECT":   SELECT,
-	"FROM":     FROM,
-	"WHERE":    WHERE,
-	"INSERT":   INSERT,
-	"UPDATE":   UPDATE,
-	"DELETE":   DELETE,
-	"CREATE":   CREATE,
-	"TABLE":    TABLE,
-	"TRUNCATE": TRUNCATE,
-	"DROP":     DROP,
-	"ALTER":    ALTER,
-	"INDEX":    INDEX,
+	"SELECT":    SELECT,
+	"FROM":      FROM,
+	"WHERE":     WHERE,
+	"INSERT":    INSERT,
+	"UPDATE":    UPDATE,
+	"DELETE":    DELETE,
+	"INTO":      INTO,
+	"VALUES":    VALUES,
+	"SET":       SET,
+	"CREATE":    CREATE,
+	"TABLE":     TABLE,
+	"TRUNCATE":  TRUNCATE,
+	"DROP":      DROP,
+	"ALTER":     ALTER,
+	"INDEX":     INDEX,
+	"IF":        IF,
+	"TEMP":      TEMP,
+	"TEMPORARY": TEMPORARY,
+	"VIEW":      VIEW,
+	"TRIGGER":   TRIGGER,
+	"RENAME":    RENAME,
+	"TO":        TO,
+	"ADD":       ADD,
+	"COLUMN":    COLUMN,
+	"BEFORE":    BEFORE,
+	"AFTER":     AFTER,
+	"INSTEAD":   INSTEAD,
+	"OF":        OF,
+	"FOR":       FOR,
+	"EACH":      EACH,
 
 	// Constraints and keys
-	"PRIMARY":        PRIMARY,
-	"KEY":            KEY,
-	"FOREIGN":        FOREIGN,
-	"REFERENCES":     REFERENCES,
-	"NOT":            NOT,
-	"NULL":           NULL,
-	"DEFAULT":        DEFAULT,
-	"AUTO_INCREMENT": AUTO_INCREMENT,
-	"AUTOINCREMENT":  AUTOINCREMENT,
-	"UNIQUE":         UNIQUE,
-	"CHECK":          CHECK,
-	"CONSTRAINT":     CONSTRAINT,
-	"COLLATE":        COLLATE,
+	"PRIMARY":    PRIMARY,
+	"KEY":        KEY,
+	"FOREIGN":    FOREIGN,
+	"REFERENCES": REFERENCES,
+	"NOT":        NOT,
+	"NULL":       NULL,
+	"DEFAULT":    DEFAULT,
+	"UNIQUE":     UNIQUE,
+	"CHECK":      CHECK,
+	"CONSTRAINT": CONSTRAINT,
+	"COLLATE":    COLLATE,
 
 	"DATABASE": DATABASE,
 	"SCHEMA":   SCHEMA,
 	"CASCADE":  CASCADE,
 	"RESTRICT": RESTRICT,
-	"CONFLICT": CONFLICT,
+	"ACTION":   ACTION,
 	"REPLACE":  REPLACE,
 	"IGNORE":   IGNORE,
-	"FAIL":     FAIL,
-	"ABORT":    ABORT,
 	"ROLLBACK": ROLLBACK,
 	"WITHOUT":  WITHOUT,
-	"ROWID":    ROWID,
 
-	// Pragma and maintenance
-	"PRAGMA":  PRAGMA,
+	// Maintenance
 	"VACUUM":  VACUUM,
 	"REINDEX": REINDEX,
 	"ANALYZE": ANALYZE,
-	"ATTACH":  ATTACH,
-	"DETACH":  DETACH,
 	"EXPLAIN": EXPLAIN,
-	"QUERY":   QUERY,
-	"PLAN":    PLAN,
 
 	// Data types
 	"INT":       INT,
@@ -898,13 +1570,21 @@ var keywords = map[string]TokenType{
 	"OUTER":     OUTER,
 	"CROSS":     CROSS,
 	"JOIN":      JOIN,
+	"NATURAL":   NATURAL,
+	"USING":     USING,
 	"ON":        ON,
 	"AS":        AS,
 	"DISTINCT":  DISTINCT,
 	"UNION":     UNION,
+	"ALL":       ALL,
 	"INTERSECT": INTERSECT,
 	"EXCEPT":    EXCEPT,
 
+	// Common table expressions
+	"WITH":         WITH,
+	"RECURSIVE":    RECURSIVE,
+	"MATERIALIZED": MATERIALIZED,
+
 	// Window functions
 	"OVER":      OVER,
 	"PARTITION": PARTITION,
@@ -916,6 +1596,12 @@ var keywords = map[string]TokenType{
 	"FOLLOWING": FOLLOWING,
 	"CURRENT":   CURRENT,
 	"ROW":       ROW,
+	"GROUPS":    GROUPS,
+	"EXCLUDE":   EXCLUDE,
+	"NO":        NO,
+	"OTHERS":    OTHERS,
+	"TIES":      TIES,
+	"FILTER":    FILTER,
 
 	// Case expressions
 	"CASE": CASE,
@@ -936,32 +1622,23 @@ var keywords = map[string]TokenType{
 	"OR":      OR,
 	"IN":      IN,
 	"LIKE":    LIKE,
-	"GLOB":    GLOB,
-	"MATCH":   MATCH,
-	"REGEXP":  REGEXP,
 	"BETWEEN": BETWEEN,
 	"IS":      IS,
-	"ISNULL":  ISNULL,
-	"NOTNULL": NOTNULL,
 	"EXISTS":  EXISTS,
+	"ESCAPE":  ESCAPE,
 
 	// Transaction
 	"BEGIN":       BEGIN,
 	"COMMIT":      COMMIT,
 	"TRANSACTION": TRANSACTION,
+	"SAVEPOINT":   SAVEPOINT,
+	"RELEASE":     RELEASE,
 
 	// Boolean literals
 	"TRUE":  TRUE,
 	"FALSE": FALSE,
 }
 
-func lookupIdent(ident string) TokenType {
-	if tok, ok := keywords[strings.ToUpper(ident)]; ok {
-		return tok
-	}
-	return IDENTIFIER
-}
-
 func (l *Lexer) GetAllTokens() []Token {
 	var tokens []Token
 	for {
@@ -975,9 +1652,9 @@ func (l *Lexer) GetAllTokens() []Token {
 }
 
 func (tt TokenType) IsKeyword() bool {
-	return tt >= SELECT && tt <= FALSE
+	return tt >= SELECT && tt <= FALSE || tt >= INTO && tt <= ESCAPE
 }
 
 func (tt TokenType) IsOperator() bool {
-	return tt >= EQUAL && tt <= CONCAT
+	return tt >= EQUAL && tt <= CONCAT || tt >= ARROW && tt <= RIGHT_SHIFT
 }
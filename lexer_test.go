@@ -1,6 +1,7 @@
 package citrinelexer
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -375,4 +376,487 @@ func TestWindowFunctions(t *testing.T) {
 				i, tt.expectedValue, tok.Value)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestTokenOffsets(t *testing.T) {
+	input := "SELECT a -- comment\n FROM t;"
+
+	tests := []struct {
+		expectedType TokenType
+		start, end   int
+	}{
+		{SELECT, 0, 6},
+		{IDENTIFIER, 7, 8},
+		{FROM, 21, 25},
+		{IDENTIFIER, 26, 27},
+		{SEMICOLON, 27, 28},
+		{EOF, 28, 28},
+		{EOF, 28, 28},
+	}
+
+	lexer := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Offset != tt.start || tok.EndOffset != tt.end {
+			t.Fatalf("tests[%d] - offsets wrong. expected=%d-%d, got=%d-%d",
+				i, tt.start, tt.end, tok.Offset, tok.EndOffset)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "SELECT name,\n  'it''s' <= [col]\n"
+
+	tests := []struct {
+		expectedType TokenType
+		line, col    int
+	}{
+		{SELECT, 1, 1},
+		{IDENTIFIER, 1, 8},
+		{COMMA, 1, 12},
+		{STRING, 2, 3},
+		{LESS_EQUAL, 2, 11},
+		{IDENTIFIER, 2, 14},
+		{EOF, 3, 1},
+	}
+
+	lexer := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.line || tok.Col != tt.col {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.line, tt.col, tok.Line, tok.Col)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "SELECT şehir, 名前 FROM müşteriler WHERE ad = 'Çağrı' \xff"
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+		col           int
+		offset        int
+	}{
+		{SELECT, "SELECT", 1, 0},
+		{IDENTIFIER, "şehir", 8, 7},
+		{COMMA, ",", 13, 13},
+		{IDENTIFIER, "名前", 15, 15},
+		{FROM, "FROM", 18, 22},
+		{IDENTIFIER, "müşteriler", 23, 27},
+		{WHERE, "WHERE", 34, 40},
+		{IDENTIFIER, "ad", 40, 46},
+		{EQUAL, "=", 43, 49},
+		{STRING, "Çağrı", 45, 51},
+		{ILLEGAL, "\xff", 53, 62},
+		{EOF, "", 54, 63},
+	}
+
+	lexer := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+
+		if tok.Col != tt.col || tok.Offset != tt.offset {
+			t.Fatalf("tests[%d] - position wrong. expected col %d, offset %d, got col %d, offset %d",
+				i, tt.col, tt.offset, tok.Col, tok.Offset)
+		}
+	}
+}
+
+func TestInvalidUTF8InString(t *testing.T) {
+	lexer := NewLexer("'ab\xc3' x")
+
+	tok := lexer.NextToken()
+	if tok.Type != ILLEGAL || tok.Value != "'ab\xc3'" {
+		t.Fatalf("expected ILLEGAL token for invalid UTF-8, got %q %q", tok.Type, tok.Value)
+	}
+
+	tok = lexer.NextToken()
+	if tok.Type != IDENTIFIER || tok.Value != "x" {
+		t.Fatalf("expected lexing to continue after invalid UTF-8, got %q %q", tok.Type, tok.Value)
+	}
+}
+
+func TestCommentTokens(t *testing.T) {
+	input := "-- migration: 001\nSELECT /* cols */ a -- trailing"
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+		line, col     int
+	}{
+		{LINE_COMMENT, "-- migration: 001", 1, 1},
+		{SELECT, "SELECT", 2, 1},
+		{BLOCK_COMMENT, "/* cols */", 2, 8},
+		{IDENTIFIER, "a", 2, 19},
+		{LINE_COMMENT, "-- trailing", 2, 21},
+		{EOF, "", 2, 32},
+	}
+
+	lexer := NewLexer(input, WithComments())
+
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+
+		if tok.Line != tt.line || tok.Col != tt.col {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.line, tt.col, tok.Line, tok.Col)
+		}
+	}
+}
+
+func TestLexerDiagnostics(t *testing.T) {
+	tests := []struct {
+		input string
+		code  LexErrorCode
+		text  string
+		line  int
+		col   int
+	}{
+		{"SELECT 'abc", UnterminatedString, "'abc", 1, 8},
+		{"SELECT \"abc", UnterminatedIdentifier, "\"abc", 1, 8},
+		{"SELECT [abc", UnterminatedIdentifier, "[abc", 1, 8},
+		{"SELECT 1 /* never closed", UnterminatedComment, "/* never closed", 1, 10},
+		{"SELECT 0x", MalformedNumber, "0x", 1, 8},
+		{"SELECT\n  1e+", MalformedNumber, "1e+", 2, 3},
+		{"SELECT 1 # 2", IllegalCharacter, "#", 1, 10},
+		{"SELECT 'a\xff'", InvalidUTF8, "'a\xff'", 1, 8},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer(tt.input)
+		lexer.GetAllTokens()
+
+		errs := lexer.Errors()
+		if len(errs) != 1 {
+			t.Fatalf("%q: expected 1 diagnostic, got %d", tt.input, len(errs))
+		}
+
+		err := errs[0]
+		if err.Code != tt.code || err.Text != tt.text || err.Line != tt.line || err.Col != tt.col {
+			t.Fatalf("%q: expected %s %q at %d:%d, got %s %q at %d:%d",
+				tt.input, tt.code, tt.text, tt.line, tt.col, err.Code, err.Text, err.Line, err.Col)
+		}
+	}
+}
+
+func TestUnterminatedStringIsIllegal(t *testing.T) {
+	lexer := NewLexer("SELECT 'abc")
+	lexer.NextToken()
+
+	tok := lexer.NextToken()
+	if tok.Type != ILLEGAL || tok.Value != "'abc" {
+		t.Fatalf("expected ILLEGAL token for unterminated string, got %q %q", tok.Type, tok.Value)
+	}
+}
+
+func TestUnterminatedCommentIsIllegal(t *testing.T) {
+	for _, opts := range [][]LexerOption{nil, {WithComments()}} {
+		lexer := NewLexer("SELECT /* x", opts...)
+		lexer.NextToken()
+
+		tok := lexer.NextToken()
+		if tok.Type != ILLEGAL || tok.Value != "/* x" {
+			t.Fatalf("expected ILLEGAL token for unterminated comment, got %q %q", tok.Type, tok.Value)
+		}
+	}
+}
+
+func TestLiteralForms(t *testing.T) {
+	input := "X'53514C' x'' ?12 @user 1_000_000 0xFF_FF 1_0.5e1_0 $name"
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+	}{
+		{BLOB_LITERAL, "53514C"},
+		{BLOB_LITERAL, ""},
+		{NUMBERED_PARAMETER, "?12"},
+		{NAMED_PARAMETER, "@user"},
+		{NUMBER, "1_000_000"},
+		{NUMBER, "0xFF_FF"},
+		{NUMBER, "1_0.5e1_0"},
+		{NAMED_PARAMETER, "$name"},
+		{EOF, ""},
+	}
+
+	lexer := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+
+	if errs := lexer.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", errs[0])
+	}
+}
+
+func TestMalformedLiterals(t *testing.T) {
+	tests := []struct {
+		input   string
+		dialect *Dialect
+		code    LexErrorCode
+	}{
+		{"X'ABC'", SQLite, MalformedBlob},
+		{"X'ZZ'", SQLite, MalformedBlob},
+		{"X'AB", SQLite, UnterminatedString},
+		{"1__000", SQLite, MalformedNumber},
+		{"1000_", SQLite, MalformedNumber},
+		{"E'\\x'", PostgreSQL, MalformedString},
+		{"E'abc", PostgreSQL, UnterminatedString},
+		{"$$abc", PostgreSQL, UnterminatedString},
+		{"`abc", PostgreSQL, IllegalCharacter},
+		{"'it\\'s", MySQL, UnterminatedString},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer(tt.input, WithLexerDialect(tt.dialect))
+
+		tok := lexer.NextToken()
+		if tok.Type != ILLEGAL {
+			t.Fatalf("%q: expected ILLEGAL token, got %q", tt.input, tok.Type)
+		}
+
+		errs := lexer.Errors()
+		if len(errs) != 1 || errs[0].Code != tt.code {
+			t.Fatalf("%q: expected a %s diagnostic, got %v", tt.input, tt.code, errs)
+		}
+	}
+}
+
+func TestDialectLexing(t *testing.T) {
+	tests := []struct {
+		dialect *Dialect
+		input   string
+		tokens  []Token
+	}{
+		{
+			SQLite,
+			"\"a\" `b` [c] 'it''s' 'a\\b' ?1 :x @y $z a->>'k'",
+			[]Token{
+				{Type: IDENTIFIER, Value: "a"},
+				{Type: IDENTIFIER, Value: "b"},
+				{Type: IDENTIFIER, Value: "c"},
+				{Type: STRING, Value: "it's"},
+				{Type: STRING, Value: "a\\b"},
+				{Type: NUMBERED_PARAMETER, Value: "?1"},
+				{Type: NAMED_PARAMETER, Value: ":x"},
+				{Type: NAMED_PARAMETER, Value: "@y"},
+				{Type: NAMED_PARAMETER, Value: "$z"},
+				{Type: IDENTIFIER, Value: "a"},
+				{Type: LONG_ARROW, Value: "->>"},
+				{Type: STRING, Value: "k"},
+			},
+		},
+		{
+			PostgreSQL,
+			"\"a\" a[1] $1 x::text E'a\\tb\\'c\\x41\\u00e9' $$it's$$ $fn$ $$ $fn$ ILIKE PRAGMA",
+			[]Token{
+				{Type: IDENTIFIER, Value: "a"},
+				{Type: IDENTIFIER, Value: "a"},
+				{Type: LBRACKET, Value: "["},
+				{Type: NUMBER, Value: "1"},
+				{Type: RBRACKET, Value: "]"},
+				{Type: NUMBERED_PARAMETER, Value: "$1"},
+				{Type: IDENTIFIER, Value: "x"},
+				{Type: DOUBLE_COLON, Value: "::"},
+				{Type: TEXT, Value: "text"},
+				{Type: STRING, Value: "a\tb'cAé"},
+				{Type: STRING, Value: "it's"},
+				{Type: STRING, Value: " $$ "},
+				{Type: ILIKE, Value: "ILIKE"},
+				{Type: IDENTIFIER, Value: "PRAGMA"},
+			},
+		},
+		{
+			MySQL,
+			"`a` \"b\" 'it\\'s\\n' 'x\\%' ? AUTO_INCREMENT a->'$.k' # note\nb",
+			[]Token{
+				{Type: IDENTIFIER, Value: "a"},
+				{Type: STRING, Value: "b"},
+				{Type: STRING, Value: "it's\n"},
+				{Type: STRING, Value: "x\\%"},
+				{Type: PARAMETER, Value: "?"},
+				{Type: AUTO_INCREMENT, Value: "AUTO_INCREMENT"},
+				{Type: IDENTIFIER, Value: "a"},
+				{Type: ARROW, Value: "->"},
+				{Type: STRING, Value: "$.k"},
+				{Type: IDENTIFIER, Value: "b"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer(tt.input, WithLexerDialect(tt.dialect))
+
+		for i, want := range tt.tokens {
+			tok := lexer.NextToken()
+			if tok.Type != want.Type || tok.Value != want.Value {
+				t.Fatalf("%s: tests[%d] - expected %s %q, got %s %q",
+					tt.dialect, i, want.Type, want.Value, tok.Type, tok.Value)
+			}
+		}
+
+		if tok := lexer.NextToken(); tok.Type != EOF {
+			t.Fatalf("%s: expected EOF, got %s", tt.dialect, tok)
+		}
+		if errs := lexer.Errors(); len(errs) != 0 {
+			t.Fatalf("%s: unexpected diagnostics: %v", tt.dialect, errs[0])
+		}
+	}
+}
+
+func TestDialectRejectsForeignSyntax(t *testing.T) {
+	tests := []struct {
+		dialect *Dialect
+		input   string
+	}{
+		{PostgreSQL, "?"},
+		{PostgreSQL, "@name"},
+		{PostgreSQL, "$name"},
+		{MySQL, "$1"},
+		{MySQL, "@name"},
+		{SQLite, "$$"},
+		{PostgreSQL, "# note"},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer(tt.input, WithLexerDialect(tt.dialect))
+		if tok := lexer.NextToken(); tok.Type != ILLEGAL {
+			t.Errorf("%s: %q - expected ILLEGAL, got %s", tt.dialect, tt.input, tok)
+		}
+	}
+}
+
+func TestHashComments(t *testing.T) {
+	lexer := NewLexer("SELECT 1 # one\n-- two", WithLexerDialect(MySQL), WithComments())
+
+	for i, want := range []Token{
+		{Type: SELECT, Value: "SELECT"},
+		{Type: NUMBER, Value: "1"},
+		{Type: LINE_COMMENT, Value: "# one"},
+		{Type: LINE_COMMENT, Value: "-- two"},
+		{Type: EOF, Value: ""},
+	} {
+		tok := lexer.NextToken()
+		if tok.Type != want.Type || tok.Value != want.Value {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, want.Type, want.Value, tok.Type, tok.Value)
+		}
+	}
+
+	group := &CommentGroup{List: []*Comment{{Text: "# one"}}}
+	if group.Text() != "one" {
+		t.Errorf("Expected comment text %q, got %q", "one", group.Text())
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	input := "a & b | c << 1 >> 2 ~d ESCAPE"
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+	}{
+		{IDENTIFIER, "a"},
+		{AMPERSAND, "&"},
+		{IDENTIFIER, "b"},
+		{PIPE, "|"},
+		{IDENTIFIER, "c"},
+		{LEFT_SHIFT, "<<"},
+		{NUMBER, "1"},
+		{RIGHT_SHIFT, ">>"},
+		{NUMBER, "2"},
+		{TILDE, "~"},
+		{IDENTIFIER, "d"},
+		{ESCAPE, "ESCAPE"},
+		{EOF, ""},
+	}
+
+	lexer := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestTokenTypeClasses(t *testing.T) {
+	// Tokens appended to the enum must still be classified.
+	for _, tt := range []TokenType{SELECT, FALSE, INTO, ILIKE, ESCAPE} {
+		if !tt.IsKeyword() {
+			t.Errorf("%s: expected a keyword", tt)
+		}
+	}
+	for _, tt := range []TokenType{EQUAL, CONCAT, ARROW, LONG_ARROW, AMPERSAND, RIGHT_SHIFT} {
+		if !tt.IsOperator() {
+			t.Errorf("%s: expected an operator", tt)
+		}
+	}
+	for _, tt := range []TokenType{IDENTIFIER, BLOB_LITERAL, NUMBERED_PARAMETER, DOUBLE_COLON} {
+		if tt.IsKeyword() || tt.IsOperator() {
+			t.Errorf("%s: expected neither a keyword nor an operator", tt)
+		}
+	}
+}
+
+func TestTokenTypeNames(t *testing.T) {
+	for tt := SELECT; tt <= DOUBLE_COLON; tt++ {
+		if name := tt.String(); strings.HasPrefix(name, "TokenType(") {
+			t.Errorf("token type %d has no name", int(tt))
+		}
+	}
+}
@@ -3,61 +3,392 @@ package citrinelexer
 import (
 	"fmt"
 	"go/token"
+	"io"
 	"strconv"
+	"strings"
 )
 
 type Parser struct {
 	lexer        *Lexer
 	currentToken Token
 	peekToken    Token
-	errors       []string
+	errors       ErrorList
+
+	// prevEnd is the end offset of the most recently consumed token and span
+	// is the source range of the last statement returned by Next.
+	prevEnd int
+	span    Span
+
+	recover bool
+	dialect *Dialect
+
+	// fset and file map token offsets to the token.Pos values stored in
+	// the AST.
+	fset     *token.FileSet
+	filename string
+	file     *token.File
+
+	// Comments, when the lexer returns them. group is the comment group
+	// still being extended, lastLine the line on which the last other token
+	// ended and stmtLine the line on which the last statement ended.
+	comments      []*CommentGroup
+	commentMap    CommentMap
+	group         *CommentGroup
+	groupLine     int
+	groupTrailing bool
+	lastLine      int
+	stmtLine      int
+
+	// Parameter numbering for the current statement.
+	maxParam int
+	params   map[string]int
 }
 
-func NewParser(lexer *Lexer) *Parser {
+// ParserOption configures a Parser created by NewParser.
+type ParserOption func(*Parser)
+
+// WithFileSet registers the parser's input with fset under filename, so
+// that node positions can be resolved with fset.Position. By default each
+// parser uses a FileSet of its own, available from FileSet.
+func WithFileSet(fset *token.FileSet, filename string) ParserOption {
+	return func(p *Parser) {
+		p.fset = fset
+		p.filename = filename
+	}
+}
+
+// WithErrorRecovery makes the parser record errors and carry on instead of
+// stopping at the first one. Next skips to the following statement after an
+// error and returns a BadStatement in place of the broken one, and missing
+// operands are returned as BadExpression nodes. The errors are available
+// from Errors.
+func WithErrorRecovery() ParserOption {
+	return func(p *Parser) {
+		p.recover = true
+	}
+}
+
+// WithDialect makes the parser, and the lexer it reads from, accept the
+// syntax of d. Without it the parser uses the lexer's dialect.
+func WithDialect(d *Dialect) ParserOption {
+	return func(p *Parser) {
+		p.dialect = d
+	}
+}
+
+// WithCommentMap makes the parser, and the lexer it reads from, keep
+// comments and record the groups attached to each node in cmap. It gives
+// Parse and ParseScript callers the comments that CommentMap returns.
+func WithCommentMap(cmap CommentMap) ParserOption {
+	return func(p *Parser) {
+		p.lexer.comments = true
+		p.commentMap = cmap
+	}
+}
+
+// Span is a half-open range of byte offsets into the parser's input.
+type Span struct {
+	Start int
+	End   int
+}
+
+func NewParser(lexer *Lexer, opts ...ParserOption) *Parser {
 	p := &Parser{
-		lexer:  lexer,
-		errors: []string{},
+		lexer:      lexer,
+		commentMap: CommentMap{},
+		params:     map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	if p.dialect != nil {
+		lexer.dialect = p.dialect
+	} else {
+		p.dialect = lexer.dialect
+	}
+	if p.fset == nil {
+		p.fset = token.NewFileSet()
+	}
+	p.file = p.fset.AddFile(p.filename, -1, len(lexer.input))
+	p.file.SetLinesForContent([]byte(lexer.input))
 	p.nextToken()
 	p.nextToken()
 	return p
 }
 
-func Parse(sql string) (Statement, error) {
+// Parse parses a single statement, optionally terminated by a semicolon.
+// Anything after it is an error; use ParseScript for multiple statements.
+// With WithErrorRecovery, the partial statement, or a BadStatement if it
+// could not be parsed, is returned together with an ErrorList of everything
+// that went wrong.
+func Parse(sql string, opts ...ParserOption) (Statement, error) {
 	lexer := NewLexer(sql)
-	parser := NewParser(lexer)
-	return parser.ParseStatement()
+	parser := NewParser(lexer, opts...)
+	start := parser.currentToken.Offset
+	stmt, err := parser.ParseStatement()
+	if err != nil {
+		if !parser.recover {
+			return nil, err
+		}
+		for parser.currentToken.Type != EOF {
+			parser.nextToken()
+		}
+		to := parser.prevEnd
+		if to < start {
+			to = start
+		}
+		bad := &BadStatement{
+			From: parser.file.Pos(start),
+			To:   parser.file.Pos(to),
+		}
+		return bad, parser.errors.Err()
+	}
+	parser.span = Span{Start: start, End: parser.prevEnd}
+
+	if parser.currentToken.Type == SEMICOLON {
+		parser.nextToken()
+	}
+	if parser.currentToken.Type != EOF {
+		err := parser.expectError(fmt.Sprintf("unexpected token after statement: %s", parser.currentToken.Type), SEMICOLON, EOF)
+		if !parser.recover {
+			return nil, err
+		}
+	}
+
+	parser.attachComments(stmt)
+	return stmt, parser.errors.Err()
+}
+
+// ParseScript parses a sequence of semicolon-separated statements. Empty
+// statements are skipped. With WithErrorRecovery, the partial result is
+// returned together with an ErrorList of everything that went wrong.
+func ParseScript(sql string, opts ...ParserOption) ([]Statement, error) {
+	parser := NewParser(NewLexer(sql), opts...)
+
+	var stmts []Statement
+	for {
+		stmt, err := parser.Next()
+		if err == io.EOF {
+			return stmts, parser.errors.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
 }
 
 func (p *Parser) nextToken() {
+	p.prevEnd = p.currentToken.EndOffset
 	p.currentToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+
+	for p.peekToken.Type == LINE_COMMENT || p.peekToken.Type == BLOCK_COMMENT {
+		p.addComment(p.peekToken)
+		p.peekToken = p.lexer.NextToken()
+	}
+	p.group = nil
+	p.lastLine = p.line(p.peekToken.EndOffset)
+}
+
+// addComment adds a comment token to the open comment group, or starts a
+// new group. As in go/ast, a comment on the same line as the preceding
+// token starts a group of its own holding only the comments on that line;
+// otherwise comments are grouped until an empty line.
+func (p *Parser) addComment(tok Token) {
+	comment := &Comment{Slash: p.file.Pos(tok.Offset), Text: tok.Value}
+	line := p.line(tok.Offset)
+
+	switch {
+	case p.group != nil && p.groupTrailing && line == p.groupLine,
+		p.group != nil && !p.groupTrailing && line <= p.groupLine+1:
+		p.group.List = append(p.group.List, comment)
+	default:
+		p.group = &CommentGroup{List: []*Comment{comment}}
+		p.groupTrailing = line == p.lastLine
+		p.comments = append(p.comments, p.group)
+	}
+	p.groupLine = p.line(tok.EndOffset)
+}
+
+// attachComments records in the comment map the group that ends on the line
+// above stmt, unless it trails the previous statement, and the group that
+// starts on the line where stmt ends. Groups inside stmt are recorded
+// under the innermost node that encloses them.
+func (p *Parser) attachComments(stmt Statement) {
+	startLine := p.line(p.span.Start)
+	endLine := p.line(p.span.End)
+
+	// Only the last few groups can be near the statement, so search from
+	// the end.
+	var doc, trailing *CommentGroup
+	var inner []*CommentGroup
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		group := p.comments[i]
+		if p.file.Offset(group.Pos()) >= p.span.End {
+			if p.file.Line(group.Pos()) == endLine {
+				trailing = group
+			}
+			continue
+		}
+		if p.file.Offset(group.End()) > p.span.Start {
+			inner = append(inner, group)
+			continue
+		}
+		if p.file.Line(group.End()) >= startLine-1 && p.file.Line(group.Pos()) > p.stmtLine {
+			doc = group
+		}
+		break
+	}
+
+	if doc != nil {
+		p.commentMap[stmt] = append(p.commentMap[stmt], doc)
+	}
+	for i := len(inner) - 1; i >= 0; i-- {
+		node := innermostNode(stmt, inner[i].Pos(), inner[i].End())
+		p.commentMap[node] = append(p.commentMap[node], inner[i])
+	}
+	if trailing != nil {
+		p.commentMap[stmt] = append(p.commentMap[stmt], trailing)
+	}
+	p.stmtLine = endLine
+}
+
+// Next parses the next statement of a script, skipping empty statements.
+// Each statement must be followed by a semicolon or the end of the input.
+// Next returns io.EOF once the input is exhausted.
+func (p *Parser) Next() (Statement, error) {
+	for p.currentToken.Type == SEMICOLON {
+		p.nextToken()
+	}
+	if p.currentToken.Type == EOF {
+		return nil, io.EOF
+	}
+
+	p.maxParam = 0
+	p.params = map[string]int{}
+
+	start := p.currentToken
+	stmt, err := p.ParseStatement()
+	if err != nil {
+		if !p.recover {
+			return nil, err
+		}
+		if p.currentToken.Offset == start.Offset {
+			p.nextToken()
+		}
+		p.synchronize()
+		stmt = &BadStatement{
+			From: p.file.Pos(start.Offset),
+			To:   p.end(),
+		}
+	}
+	p.span = Span{Start: start.Offset, End: p.prevEnd}
+
+	if p.currentToken.Type != SEMICOLON && p.currentToken.Type != EOF {
+		err := p.expectError(fmt.Sprintf("expected ; after statement, got %s", p.currentToken.Type), SEMICOLON, EOF)
+		if !p.recover {
+			return nil, err
+		}
+		p.synchronize()
+	}
+
+	p.attachComments(stmt)
+	return stmt, nil
+}
+
+// synchronize skips tokens up to the next semicolon or the keyword that
+// starts the next statement.
+func (p *Parser) synchronize() {
+	for p.currentToken.Type != SEMICOLON && p.currentToken.Type != EOF && !p.isStatementStart() {
+		p.nextToken()
+	}
+}
+
+// isStatementStart reports whether the current token can begin a statement.
+// END is left out because it far more often closes a CASE expression.
+func (p *Parser) isStatementStart() bool {
+	switch p.currentToken.Type {
+	case SELECT, WITH, CREATE, INSERT, UPDATE, DELETE, DROP, ALTER,
+		PRAGMA, VACUUM, EXPLAIN, ATTACH, DETACH, REINDEX, ANALYZE,
+		BEGIN, COMMIT, ROLLBACK, SAVEPOINT, RELEASE:
+		return true
+	case REPLACE:
+		// REPLACE is also a function name.
+		return p.peekToken.Type == INTO
+	default:
+		return false
+	}
+}
+
+// Span returns the source range of the statement most recently returned by
+// Next, excluding its terminating semicolon.
+func (p *Parser) Span() Span {
+	return p.span
 }
 
 func (p *Parser) ParseStatement() (Statement, error) {
 	switch p.currentToken.Type {
 	case SELECT:
-		return p.parseSelectStatement()
+		return p.parseQuery()
+	case WITH:
+		return p.parseWithStatement()
 	case CREATE:
 		return p.parseCreateStatement()
-	case INSERT:
+	case INSERT, REPLACE:
 		return p.parseInsertStatement()
 	case UPDATE:
 		return p.parseUpdateStatement()
 	case DELETE:
 		return p.parseDeleteStatement()
+	case DROP:
+		return p.parseDropStatement()
+	case ALTER:
+		return p.parseAlterTableStatement()
+	case PRAGMA:
+		return p.parsePragmaStatement()
+	case VACUUM:
+		return p.parseVacuumStatement()
+	case EXPLAIN:
+		return p.parseExplainStatement()
+	case ATTACH:
+		return p.parseAttachStatement()
+	case DETACH:
+		return p.parseDetachStatement()
+	case REINDEX:
+		return p.parseReindexStatement()
+	case ANALYZE:
+		return p.parseAnalyzeStatement()
+	case BEGIN:
+		return p.parseBeginStatement()
+	case COMMIT, END:
+		return p.parseCommitStatement()
+	case ROLLBACK:
+		return p.parseRollbackStatement()
+	case SAVEPOINT:
+		return p.parseSavepointStatement()
+	case RELEASE:
+		return p.parseReleaseStatement()
 	default:
-		return nil, fmt.Errorf("unexpected token: %s", p.currentToken.Type)
+		return nil, p.errorf("unexpected token: %s", p.currentToken.Type)
 	}
 }
 
 func (p *Parser) parseSelectStatement() (*SelectStatement, error) {
 	stmt := &SelectStatement{
-		Select: token.Pos(p.currentToken.Col),
+		Select: p.pos(),
 	}
 
 	if !p.expectToken(SELECT) {
-		return nil, fmt.Errorf("expected SELECT")
+		return nil, p.expectError("expected SELECT", SELECT)
+	}
+
+	switch p.currentToken.Type {
+	case DISTINCT:
+		stmt.Distinct = true
+		p.nextToken()
+	case ALL:
+		p.nextToken()
 	}
 
 	fields, err := p.parseSelectFields()
@@ -68,7 +399,7 @@ func (p *Parser) parseSelectStatement() (*SelectStatement, error) {
 
 	if p.currentToken.Type == FROM {
 		p.nextToken()
-		from, err := p.parseTableRef()
+		from, err := p.parseFromClause()
 		if err != nil {
 			return nil, err
 		}
@@ -84,46 +415,38 @@ func (p *Parser) parseSelectStatement() (*SelectStatement, error) {
 		stmt.Where = where
 	}
 
-	if p.currentToken.Type == ORDER {
+	if p.currentToken.Type == GROUP {
 		p.nextToken()
 		if !p.expectToken(BY) {
-			return nil, fmt.Errorf("expected BY after ORDER")
+			return nil, p.expectError("expected BY after GROUP", BY)
 		}
-		orderBy, err := p.parseOrderBy()
+		groupBy, err := p.parseExpressionList()
 		if err != nil {
 			return nil, err
 		}
-		stmt.OrderBy = orderBy
+		stmt.GroupBy = groupBy
 	}
 
-	if p.currentToken.Type == LIMIT {
+	if p.currentToken.Type == HAVING {
+		if stmt.GroupBy == nil && !p.dialect.HavingWithoutGroupBy {
+			return nil, p.errorf("HAVING clause requires GROUP BY")
+		}
 		p.nextToken()
-		limit, err := p.parseLimitClause()
+		having, err := p.parseExpression()
 		if err != nil {
 			return nil, err
 		}
-		stmt.Limit = limit
+		stmt.Having = having
 	}
 
-	return stmt, nil
-}
-
-func (p *Parser) parseSelectFields() ([]Expression, error) {
-	var fields []Expression
-
-	if p.currentToken.Type == ASTERISK {
-		fields = append(fields, &Identifier{
-			Name: "*",
-			Pos_: token.Pos(p.currentToken.Col),
-		})
+	if p.currentToken.Type == WINDOW {
 		p.nextToken()
-	} else {
 		for {
-			expr, err := p.parseExpression()
+			window, err := p.parseNamedWindow()
 			if err != nil {
 				return nil, err
 			}
-			fields = append(fields, expr)
+			stmt.Windows = append(stmt.Windows, window)
 
 			if p.currentToken.Type != COMMA {
 				break
@@ -132,169 +455,406 @@ func (p *Parser) parseSelectFields() ([]Expression, error) {
 		}
 	}
 
-	return fields, nil
+	stmt.End_ = p.end()
+	return stmt, nil
 }
 
-func (p *Parser) parseCreateStatement() (*CreateTableStatement, error) {
-	stmt := &CreateTableStatement{
-		Create: token.Pos(p.currentToken.Col),
+// parseQuery parses a SELECT, or a compound SELECT when the first core is
+// followed by UNION, INTERSECT or EXCEPT. A trailing ORDER BY and LIMIT
+// belong to the whole compound rather than to its last core.
+func (p *Parser) parseQuery() (Query, error) {
+	if p.currentToken.Type != WITH {
+		return p.parseQueryWith(nil)
 	}
 
-	if !p.expectToken(CREATE) {
-		return nil, fmt.Errorf("expected CREATE")
+	with, err := p.parseWithClause()
+	if err != nil {
+		return nil, err
 	}
+	if p.currentToken.Type != SELECT {
+		return nil, p.expectError("expected SELECT after WITH clause", SELECT)
+	}
+	return p.parseQueryWith(with)
+}
 
-	if !p.expectToken(TABLE) {
-		return nil, fmt.Errorf("expected TABLE")
+// parseQueryWith parses the rest of a query whose WITH clause, if any, has
+// already been read.
+func (p *Parser) parseQueryWith(with *WithClause) (Query, error) {
+	first, err := p.parseSelectStatement()
+	if err != nil {
+		return nil, err
 	}
 
-	if p.currentToken.Type != IDENTIFIER {
-		return nil, fmt.Errorf("expected table name")
+	if !p.isCompoundOperator() {
+		first.With = with
+		first.OrderBy, first.Limit, err = p.parseOrderByAndLimit()
+		if err != nil {
+			return nil, err
+		}
+		first.End_ = p.end()
+		return first, nil
 	}
 
-	stmt.Table = &Identifier{
-		Name: p.currentToken.Value,
-		Pos_: token.Pos(p.currentToken.Col),
+	compound := &CompoundSelect{
+		With:    with,
+		Selects: []*SelectStatement{first},
 	}
-	p.nextToken()
 
-	if !p.expectToken(LPAREN) {
-		return nil, fmt.Errorf("expected (")
+	for p.isCompoundOperator() {
+		op := CompoundOperator{Type: p.currentToken.Type.String()}
+		p.nextToken()
+		if op.Type == "UNION" && p.currentToken.Type == ALL {
+			op.All = true
+			p.nextToken()
+		}
+
+		if p.currentToken.Type != SELECT {
+			return nil, p.expectError(fmt.Sprintf("expected SELECT after %s", op.Type), SELECT)
+		}
+		next, err := p.parseSelectStatement()
+		if err != nil {
+			return nil, err
+		}
+
+		compound.Operators = append(compound.Operators, op)
+		compound.Selects = append(compound.Selects, next)
 	}
 
-	columns, err := p.parseColumnDefs()
+	compound.OrderBy, compound.Limit, err = p.parseOrderByAndLimit()
 	if err != nil {
 		return nil, err
 	}
-	stmt.Columns = columns
 
-	if !p.expectToken(RPAREN) {
-		return nil, fmt.Errorf("expected )")
+	compound.End_ = p.end()
+	return compound, nil
+}
+
+// parseWithStatement parses a statement that starts with a WITH clause.
+func (p *Parser) parseWithStatement() (Statement, error) {
+	with, err := p.parseWithClause()
+	if err != nil {
+		return nil, err
 	}
 
-	return stmt, nil
+	switch p.currentToken.Type {
+	case SELECT:
+		return p.parseQueryWith(with)
+	case INSERT, REPLACE:
+		stmt, err := p.parseInsertStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.With = with
+		stmt.End_ = p.end()
+		return stmt, nil
+	case UPDATE:
+		stmt, err := p.parseUpdateStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.With = with
+		stmt.End_ = p.end()
+		return stmt, nil
+	case DELETE:
+		stmt, err := p.parseDeleteStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.With = with
+		stmt.End_ = p.end()
+		return stmt, nil
+	default:
+		return nil, p.expectError("expected SELECT, INSERT, UPDATE or DELETE after WITH clause", SELECT, INSERT, UPDATE, DELETE)
+	}
 }
 
-func (p *Parser) parseColumnDefs() ([]*ColumnDef, error) {
-	var columns []*ColumnDef
+func (p *Parser) parseWithClause() (*WithClause, error) {
+	with := &WithClause{
+		With: p.pos(),
+	}
+
+	if !p.expectToken(WITH) {
+		return nil, p.expectError("expected WITH", WITH)
+	}
+
+	if p.currentToken.Type == RECURSIVE {
+		with.Recursive = true
+		p.nextToken()
+	}
 
-	for p.currentToken.Type != RPAREN && p.currentToken.Type != EOF {
-		col, err := p.parseColumnDef()
+	for {
+		cte, err := p.parseCommonTableExpr()
 		if err != nil {
 			return nil, err
 		}
-		columns = append(columns, col)
+		with.CTEs = append(with.CTEs, cte)
 
-		if p.currentToken.Type == COMMA {
-			p.nextToken()
-		} else {
+		if p.currentToken.Type != COMMA {
 			break
 		}
+		p.nextToken()
 	}
 
-	return columns, nil
+	with.End_ = p.end()
+	return with, nil
 }
 
-func (p *Parser) parseColumnDef() (*ColumnDef, error) {
+func (p *Parser) parseCommonTableExpr() (*CommonTableExpr, error) {
 	if p.currentToken.Type != IDENTIFIER {
-		return nil, fmt.Errorf("expected column name")
+		return nil, p.expectError("expected common table expression name", IDENTIFIER)
 	}
 
-	col := &ColumnDef{
+	cte := &CommonTableExpr{
 		Name: &Identifier{
 			Name: p.currentToken.Value,
-			Pos_: token.Pos(p.currentToken.Col),
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
 		},
 	}
 	p.nextToken()
 
-	if p.isDataType() {
-		col.Type = p.currentToken.Value
+	if p.currentToken.Type == LPAREN {
 		p.nextToken()
-	}
-
-	for p.isConstraintKeyword() {
-		constraint, err := p.parseConstraint()
+		columns, err := p.parseIdentifierList()
 		if err != nil {
 			return nil, err
 		}
-		col.Constraints = append(col.Constraints, constraint)
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after column list", RPAREN)
+		}
+		cte.Columns = columns
 	}
 
-	return col, nil
-}
-
-func (p *Parser) parseInsertStatement() (*InsertStatement, error) {
-	stmt := &InsertStatement{
-		Insert: token.Pos(p.currentToken.Col),
+	if !p.expectToken(AS) {
+		return nil, p.expectError("expected AS after common table expression name", AS)
 	}
 
-	if !p.expectToken(INSERT) {
-		return nil, fmt.Errorf("expected INSERT")
+	switch p.currentToken.Type {
+	case MATERIALIZED:
+		cte.Materialized = "MATERIALIZED"
+		p.nextToken()
+	case NOT:
+		p.nextToken()
+		if !p.expectToken(MATERIALIZED) {
+			return nil, p.expectError("expected MATERIALIZED after NOT", MATERIALIZED)
+		}
+		cte.Materialized = "NOT MATERIALIZED"
 	}
 
-	if p.currentToken.Type == INSERT {
-		p.nextToken()
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( before common table expression body", LPAREN)
 	}
 
-	if p.currentToken.Type != IDENTIFIER {
-		return nil, fmt.Errorf("expected table name")
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
 	}
+	cte.Query = query
 
-	stmt.Table = &Identifier{
-		Name: p.currentToken.Value,
-		Pos_: token.Pos(p.currentToken.Col),
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after common table expression body", RPAREN)
 	}
-	p.nextToken()
 
-	return stmt, nil
+	cte.End_ = p.end()
+	return cte, nil
 }
 
-func (p *Parser) parseUpdateStatement() (*UpdateStatement, error) {
-	stmt := &UpdateStatement{
-		Update: token.Pos(p.currentToken.Col),
-	}
+func (p *Parser) parseOrderByAndLimit() ([]OrderByItem, *LimitClause, error) {
+	var orderBy []OrderByItem
+	var limit *LimitClause
 
-	if !p.expectToken(UPDATE) {
-		return nil, fmt.Errorf("expected UPDATE")
+	if p.currentToken.Type == ORDER {
+		p.nextToken()
+		if !p.expectToken(BY) {
+			return nil, nil, p.expectError("expected BY after ORDER", BY)
+		}
+		items, err := p.parseOrderBy()
+		if err != nil {
+			return nil, nil, err
+		}
+		orderBy = items
 	}
 
-	if p.currentToken.Type != IDENTIFIER {
-		return nil, fmt.Errorf("expected table name")
+	if p.currentToken.Type == LIMIT {
+		p.nextToken()
+		clause, err := p.parseLimitClause()
+		if err != nil {
+			return nil, nil, err
+		}
+		limit = clause
 	}
 
-	stmt.Table = &Identifier{
-		Name: p.currentToken.Value,
-		Pos_: token.Pos(p.currentToken.Col),
+	return orderBy, limit, nil
+}
+
+func (p *Parser) parseSelectFields() ([]Expression, error) {
+	var fields []Expression
+
+	if p.currentToken.Type == ASTERISK {
+		fields = append(fields, &Identifier{
+			Name: "*",
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		})
+		p.nextToken()
+	} else {
+		for {
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, expr)
+
+			if p.currentToken.Type != COMMA {
+				break
+			}
+			p.nextToken()
+		}
 	}
-	p.nextToken()
 
-	return stmt, nil
+	return fields, nil
 }
 
-func (p *Parser) parseDeleteStatement() (*DeleteStatement, error) {
-	stmt := &DeleteStatement{
-		Delete: token.Pos(p.currentToken.Col),
-	}
+// parseCreateStatement reads CREATE and its TEMP or UNIQUE modifier, then
+// dispatches on the kind of object being created.
+func (p *Parser) parseCreateStatement() (Statement, error) {
+	pos := p.pos()
 
-	if !p.expectToken(DELETE) {
-		return nil, fmt.Errorf("expected DELETE")
+	if !p.expectToken(CREATE) {
+		return nil, p.expectError("expected CREATE", CREATE)
 	}
 
-	if !p.expectToken(FROM) {
-		return nil, fmt.Errorf("expected FROM")
+	temporary := false
+	if p.currentToken.Type == TEMP || p.currentToken.Type == TEMPORARY {
+		temporary = true
+		p.nextToken()
 	}
 
-	if p.currentToken.Type != IDENTIFIER {
-		return nil, fmt.Errorf("expected table name")
+	unique := false
+	if !temporary && p.currentToken.Type == UNIQUE {
+		unique = true
+		p.nextToken()
+		if p.currentToken.Type != INDEX {
+			return nil, p.expectError("expected INDEX after UNIQUE", INDEX)
+		}
 	}
 
-	stmt.From = &Identifier{
+	switch p.currentToken.Type {
+	case TABLE:
+		return p.parseCreateTableStatement(pos, temporary)
+	case INDEX:
+		if temporary {
+			return nil, p.errorf("unexpected TEMP before INDEX")
+		}
+		return p.parseCreateIndexStatement(pos, unique)
+	case VIEW:
+		return p.parseCreateViewStatement(pos, temporary)
+	case TRIGGER:
+		return p.parseCreateTriggerStatement(pos, temporary)
+	default:
+		return nil, p.expectError("expected TABLE, INDEX, VIEW or TRIGGER after CREATE", TABLE, INDEX, VIEW, TRIGGER)
+	}
+}
+
+func (p *Parser) parseCreateTableStatement(pos token.Pos, temporary bool) (*CreateTableStatement, error) {
+	stmt := &CreateTableStatement{
+		Create:    pos,
+		Temporary: temporary,
+	}
+
+	if !p.expectToken(TABLE) {
+		return nil, p.expectError("expected TABLE", TABLE)
+	}
+
+	ifNotExists, err := p.parseIfNotExists()
+	if err != nil {
+		return nil, err
+	}
+	stmt.IfNotExists = ifNotExists
+
+	schema, table, err := p.parseQualifiedName("table")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Schema = schema
+	stmt.Table = table
+
+	if p.currentToken.Type == AS {
+		p.nextToken()
+		query, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		stmt.AsSelect = query
+		stmt.End_ = p.end()
+		return stmt, nil
+	}
+
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected (", LPAREN)
+	}
+
+	if err := p.parseTableElements(stmt); err != nil {
+		return nil, err
+	}
+
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected )", RPAREN)
+	}
+
+	if p.currentToken.Type == WITHOUT {
+		p.nextToken()
+		if !p.expectToken(ROWID) {
+			return nil, p.expectError("expected ROWID after WITHOUT", ROWID)
+		}
+		stmt.WithoutRowID = true
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseCreateIndexStatement(pos token.Pos, unique bool) (*CreateIndexStatement, error) {
+	stmt := &CreateIndexStatement{
+		Create: pos,
+		Unique: unique,
+	}
+
+	if !p.expectToken(INDEX) {
+		return nil, p.expectError("expected INDEX", INDEX)
+	}
+
+	ifNotExists, err := p.parseIfNotExists()
+	if err != nil {
+		return nil, err
+	}
+	stmt.IfNotExists = ifNotExists
+
+	stmt.Schema, stmt.Name, err = p.parseQualifiedName("index")
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.expectToken(ON) {
+		return nil, p.expectError("expected ON after index name", ON)
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected table name", IDENTIFIER)
+	}
+	stmt.Table = &Identifier{
 		Name: p.currentToken.Value,
-		Pos_: token.Pos(p.currentToken.Col),
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
 	}
 	p.nextToken()
 
+	columns, err := p.parseIndexedColumns()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Columns = columns
+
 	if p.currentToken.Type == WHERE {
 		p.nextToken()
 		where, err := p.parseExpression()
@@ -304,159 +864,2206 @@ func (p *Parser) parseDeleteStatement() (*DeleteStatement, error) {
 		stmt.Where = where
 	}
 
+	stmt.End_ = p.end()
 	return stmt, nil
 }
 
-func (p *Parser) parseExpression() (Expression, error) {
-	return p.parseComparison()
-}
+func (p *Parser) parseCreateViewStatement(pos token.Pos, temporary bool) (*CreateViewStatement, error) {
+	stmt := &CreateViewStatement{
+		Create:    pos,
+		Temporary: temporary,
+	}
+
+	if !p.expectToken(VIEW) {
+		return nil, p.expectError("expected VIEW", VIEW)
+	}
 
-func (p *Parser) parseComparison() (Expression, error) {
-	left, err := p.parsePrimary()
+	ifNotExists, err := p.parseIfNotExists()
 	if err != nil {
 		return nil, err
 	}
+	stmt.IfNotExists = ifNotExists
 
-	if p.isComparisonOperator() {
-		operator := p.currentToken.Value
-		pos := token.Pos(p.currentToken.Col)
-		p.nextToken()
+	stmt.Schema, stmt.Name, err = p.parseQualifiedName("view")
+	if err != nil {
+		return nil, err
+	}
 
-		right, err := p.parsePrimary()
+	if p.currentToken.Type == LPAREN {
+		columns, err := p.parseParenIdentifierList()
 		if err != nil {
 			return nil, err
 		}
+		stmt.Columns = columns
+	}
 
-		return &BinaryExpression{
-			Left:     left,
-			Operator: operator,
-			Right:    right,
-			Pos_:     pos,
-		}, nil
+	if !p.expectToken(AS) {
+		return nil, p.expectError("expected AS after view name", AS)
+	}
+
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
 	}
+	stmt.Query = query
 
-	return left, nil
+	stmt.End_ = p.end()
+	return stmt, nil
 }
 
-func (p *Parser) parsePrimary() (Expression, error) {
-	var name string
-	var pos token.Pos
+func (p *Parser) parseCreateTriggerStatement(pos token.Pos, temporary bool) (*CreateTriggerStatement, error) {
+	stmt := &CreateTriggerStatement{
+		Create:    pos,
+		Temporary: temporary,
+	}
+
+	if !p.expectToken(TRIGGER) {
+		return nil, p.expectError("expected TRIGGER", TRIGGER)
+	}
+
+	ifNotExists, err := p.parseIfNotExists()
+	if err != nil {
+		return nil, err
+	}
+	stmt.IfNotExists = ifNotExists
+
+	stmt.Schema, stmt.Name, err = p.parseQualifiedName("trigger")
+	if err != nil {
+		return nil, err
+	}
 
 	switch p.currentToken.Type {
-	case IDENTIFIER, COUNT, SUM, AVG, MIN, MAX:
-		name = p.currentToken.Value
-		pos = token.Pos(p.currentToken.Col)
+	case BEFORE, AFTER:
+		stmt.Time = p.currentToken.Type.String()
+		p.nextToken()
+	case INSTEAD:
+		p.nextToken()
+		if !p.expectToken(OF) {
+			return nil, p.expectError("expected OF after INSTEAD", OF)
+		}
+		stmt.Time = "INSTEAD OF"
+	}
 
-		if p.currentToken.Type == LPAREN {
+	switch p.currentToken.Type {
+	case DELETE, INSERT:
+		stmt.Event = p.currentToken.Type.String()
+		p.nextToken()
+	case UPDATE:
+		stmt.Event = "UPDATE"
+		p.nextToken()
+		if p.currentToken.Type == OF {
 			p.nextToken()
-			args := []Expression{}
-
-			if p.currentToken.Type != RPAREN {
-				for {
-					arg, err := p.parseExpression()
-					if err != nil {
-						return nil, err
-					}
-					args = append(args, arg)
-
-					if p.currentToken.Type != COMMA {
-						break
-					}
-					p.nextToken()
-				}
+			columns, err := p.parseIdentifierList()
+			if err != nil {
+				return nil, err
 			}
+			stmt.UpdateOf = columns
+		}
+	default:
+		return nil, p.expectError("expected DELETE, INSERT or UPDATE in trigger", DELETE, INSERT, UPDATE)
+	}
 
-			if !p.expectToken(RPAREN) {
-				return nil, fmt.Errorf("expected )")
-			}
+	if !p.expectToken(ON) {
+		return nil, p.expectError("expected ON in trigger", ON)
+	}
 
-			return &FunctionCall{
-				Name: name,
-				Args: args,
-				Pos_: pos,
-			}, nil
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected table name", IDENTIFIER)
+	}
+	stmt.Table = &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == FOR {
+		p.nextToken()
+		if !p.expectToken(EACH) {
+			return nil, p.expectError("expected EACH after FOR", EACH)
+		}
+		if !p.expectToken(ROW) {
+			return nil, p.expectError("expected ROW after FOR EACH", ROW)
 		}
+		stmt.ForEachRow = true
+	}
 
-		return &Identifier{
-			Name: name,
-			Pos_: pos,
-		}, nil
+	if p.currentToken.Type == WHEN {
+		p.nextToken()
+		when, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.When = when
+	}
 
-	case STRING:
-		value := p.currentToken.Value
-		pos := token.Pos(p.currentToken.Col)
+	if !p.expectToken(BEGIN) {
+		return nil, p.expectError("expected BEGIN before trigger body", BEGIN)
+	}
+
+	for p.currentToken.Type != END {
+		switch p.currentToken.Type {
+		case SELECT, WITH, INSERT, UPDATE, DELETE:
+		default:
+			return nil, p.expectError("expected SELECT, INSERT, UPDATE or DELETE in trigger body", SELECT, INSERT, UPDATE, DELETE)
+		}
+
+		bodyStmt, err := p.ParseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Body = append(stmt.Body, bodyStmt)
+
+		if !p.expectToken(SEMICOLON) {
+			return nil, p.expectError("expected ; after trigger body statement", SEMICOLON)
+		}
+	}
+	p.nextToken()
+
+	if len(stmt.Body) == 0 {
+		return nil, p.errorf("trigger body must contain at least one statement")
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseDropStatement() (*DropStatement, error) {
+	stmt := &DropStatement{
+		Drop: p.pos(),
+	}
+
+	if !p.expectToken(DROP) {
+		return nil, p.expectError("expected DROP", DROP)
+	}
+
+	switch p.currentToken.Type {
+	case TABLE, INDEX, VIEW, TRIGGER:
+		stmt.Kind = p.currentToken.Type.String()
 		p.nextToken()
-		return &StringLiteral{
-			Value: value,
-			Pos_:  pos,
-		}, nil
+	default:
+		return nil, p.expectError("expected TABLE, INDEX, VIEW or TRIGGER after DROP", TABLE, INDEX, VIEW, TRIGGER)
+	}
 
-	case NUMBER:
-		value := p.currentToken.Value
-		pos := token.Pos(p.currentToken.Col)
+	if p.currentToken.Type == IF {
 		p.nextToken()
-		return &NumberLiteral{
-			Value: value,
-			Pos_:  pos,
-		}, nil
+		if !p.expectToken(EXISTS) {
+			return nil, p.expectError("expected EXISTS after IF", EXISTS)
+		}
+		stmt.IfExists = true
+	}
 
-	case TRUE, FALSE:
-		value := p.currentToken.Type == TRUE
-		pos := token.Pos(p.currentToken.Col)
+	schema, name, err := p.parseQualifiedName(strings.ToLower(stmt.Kind))
+	if err != nil {
+		return nil, err
+	}
+	stmt.Schema = schema
+	stmt.Name = name
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseAlterTableStatement() (*AlterTableStatement, error) {
+	stmt := &AlterTableStatement{
+		Alter: p.pos(),
+	}
+
+	if !p.expectToken(ALTER) {
+		return nil, p.expectError("expected ALTER", ALTER)
+	}
+
+	if !p.expectToken(TABLE) {
+		return nil, p.expectError("expected TABLE after ALTER", TABLE)
+	}
+
+	schema, table, err := p.parseQualifiedName("table")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Schema = schema
+	stmt.Table = table
+
+	switch p.currentToken.Type {
+	case RENAME:
 		p.nextToken()
-		return &BooleanLiteral{
-			Value: value,
-			Pos_:  pos,
-		}, nil
+		if p.currentToken.Type == TO {
+			p.nextToken()
+			stmt.Action = "RENAME TO"
+			stmt.NewName, err = p.parseAlterIdentifier("new table name")
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
 
-	case PARAMETER:
-		pos := token.Pos(p.currentToken.Col)
+		if p.currentToken.Type == COLUMN {
+			p.nextToken()
+		}
+		stmt.Action = "RENAME COLUMN"
+		stmt.Column, err = p.parseAlterIdentifier("column name")
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(TO) {
+			return nil, p.expectError("expected TO after column name", TO)
+		}
+		stmt.NewName, err = p.parseAlterIdentifier("new column name")
+		if err != nil {
+			return nil, err
+		}
+
+	case ADD:
 		p.nextToken()
-		return &Parameter{
-			Name: "",
-			Pos_: pos,
-		}, nil
+		if p.currentToken.Type == COLUMN {
+			p.nextToken()
+		}
+		stmt.Action = "ADD COLUMN"
+		stmt.ColumnDef, err = p.parseColumnDef()
+		if err != nil {
+			return nil, err
+		}
 
-	case NAMED_PARAMETER:
-		name := p.currentToken.Value
-		pos := token.Pos(p.currentToken.Col)
+	case DROP:
 		p.nextToken()
-		return &Parameter{
-			Name: name,
-			Pos_: pos,
-		}, nil
+		if p.currentToken.Type == COLUMN {
+			p.nextToken()
+		}
+		stmt.Action = "DROP COLUMN"
+		stmt.Column, err = p.parseAlterIdentifier("column name")
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, p.expectError("expected RENAME, ADD or DROP after table name", RENAME, ADD, DROP)
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseAlterIdentifier(what string) (*Identifier, error) {
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError(fmt.Sprintf("expected %s", what), IDENTIFIER)
+	}
+	ident := &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+	return ident, nil
+}
+
+func (p *Parser) parsePragmaStatement() (*PragmaStatement, error) {
+	stmt := &PragmaStatement{
+		Pragma: p.pos(),
+	}
+
+	if !p.expectToken(PRAGMA) {
+		return nil, p.expectError("expected PRAGMA", PRAGMA)
+	}
+
+	schema, name, err := p.parseQualifiedName("pragma")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Schema = schema
+	stmt.Name = name
+
+	switch p.currentToken.Type {
+	case EQUAL:
+		p.nextToken()
+		value, err := p.parsePragmaValue()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Value = value
+	case LPAREN:
+		p.nextToken()
+		value, err := p.parsePragmaValue()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Value = value
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after pragma value", RPAREN)
+		}
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+// parsePragmaValue parses a signed number, string or name. Keywords such
+// as ON, NO or FULL are accepted as names.
+func (p *Parser) parsePragmaValue() (Expression, error) {
+	if p.currentToken.Type.IsKeyword() && p.currentToken.Type != TRUE && p.currentToken.Type != FALSE {
+		ident := &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}
+		p.nextToken()
+		return ident, nil
+	}
+	return p.parseUnary()
+}
+
+func (p *Parser) parseVacuumStatement() (*VacuumStatement, error) {
+	stmt := &VacuumStatement{
+		Vacuum: p.pos(),
+	}
+
+	if !p.expectToken(VACUUM) {
+		return nil, p.expectError("expected VACUUM", VACUUM)
+	}
+
+	if p.currentToken.Type == IDENTIFIER {
+		stmt.Schema = &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}
+		p.nextToken()
+	}
+
+	if p.currentToken.Type == INTO {
+		p.nextToken()
+		into, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Into = into
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseExplainStatement() (*ExplainStatement, error) {
+	stmt := &ExplainStatement{
+		Explain: p.pos(),
+	}
+
+	if !p.expectToken(EXPLAIN) {
+		return nil, p.expectError("expected EXPLAIN", EXPLAIN)
+	}
+
+	if p.currentToken.Type == QUERY {
+		p.nextToken()
+		if !p.expectToken(PLAN) {
+			return nil, p.expectError("expected PLAN after QUERY", PLAN)
+		}
+		stmt.QueryPlan = true
+	}
+
+	if p.currentToken.Type == EXPLAIN {
+		return nil, p.errorf("EXPLAIN cannot be nested")
+	}
+
+	inner, err := p.ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Statement = inner
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseAttachStatement() (*AttachStatement, error) {
+	stmt := &AttachStatement{
+		Attach: p.pos(),
+	}
+
+	if !p.expectToken(ATTACH) {
+		return nil, p.expectError("expected ATTACH", ATTACH)
+	}
+
+	if p.currentToken.Type == DATABASE {
+		p.nextToken()
+	}
+
+	database, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Database = database
+
+	if !p.expectToken(AS) {
+		return nil, p.expectError("expected AS after database", AS)
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected schema name", IDENTIFIER)
+	}
+	stmt.Schema = &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseDetachStatement() (*DetachStatement, error) {
+	stmt := &DetachStatement{
+		Detach: p.pos(),
+	}
+
+	if !p.expectToken(DETACH) {
+		return nil, p.expectError("expected DETACH", DETACH)
+	}
+
+	if p.currentToken.Type == DATABASE {
+		p.nextToken()
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected schema name", IDENTIFIER)
+	}
+	stmt.Schema = &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseReindexStatement() (*ReindexStatement, error) {
+	stmt := &ReindexStatement{
+		Reindex: p.pos(),
+	}
+
+	if !p.expectToken(REINDEX) {
+		return nil, p.expectError("expected REINDEX", REINDEX)
+	}
+
+	if p.currentToken.Type == IDENTIFIER {
+		schema, name, err := p.parseQualifiedName("collation, table or index")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Schema = schema
+		stmt.Name = name
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseAnalyzeStatement() (*AnalyzeStatement, error) {
+	stmt := &AnalyzeStatement{
+		Analyze: p.pos(),
+	}
+
+	if !p.expectToken(ANALYZE) {
+		return nil, p.expectError("expected ANALYZE", ANALYZE)
+	}
+
+	if p.currentToken.Type == IDENTIFIER {
+		schema, name, err := p.parseQualifiedName("schema, table or index")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Schema = schema
+		stmt.Name = name
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseBeginStatement() (*BeginStatement, error) {
+	stmt := &BeginStatement{
+		Begin: p.pos(),
+	}
+
+	if !p.expectToken(BEGIN) {
+		return nil, p.expectError("expected BEGIN", BEGIN)
+	}
+
+	switch p.currentToken.Type {
+	case DEFERRED, IMMEDIATE, EXCLUSIVE:
+		stmt.Mode = p.currentToken.Type.String()
+		p.nextToken()
+	}
+
+	if p.currentToken.Type == TRANSACTION {
+		p.nextToken()
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseCommitStatement() (*CommitStatement, error) {
+	stmt := &CommitStatement{
+		Commit: p.pos(),
+	}
+
+	if p.currentToken.Type != COMMIT && p.currentToken.Type != END {
+		return nil, p.expectError("expected COMMIT or END", COMMIT, END)
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == TRANSACTION {
+		p.nextToken()
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseRollbackStatement() (*RollbackStatement, error) {
+	stmt := &RollbackStatement{
+		Rollback: p.pos(),
+	}
+
+	if !p.expectToken(ROLLBACK) {
+		return nil, p.expectError("expected ROLLBACK", ROLLBACK)
+	}
+
+	if p.currentToken.Type == TRANSACTION {
+		p.nextToken()
+	}
+
+	if p.currentToken.Type != TO {
+		stmt.End_ = p.end()
+		return stmt, nil
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == SAVEPOINT {
+		p.nextToken()
+	}
+
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Savepoint = name
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseSavepointStatement() (*SavepointStatement, error) {
+	stmt := &SavepointStatement{
+		Savepoint: p.pos(),
+	}
+
+	if !p.expectToken(SAVEPOINT) {
+		return nil, p.expectError("expected SAVEPOINT", SAVEPOINT)
+	}
+
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = name
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseReleaseStatement() (*ReleaseStatement, error) {
+	stmt := &ReleaseStatement{
+		Release: p.pos(),
+	}
+
+	if !p.expectToken(RELEASE) {
+		return nil, p.expectError("expected RELEASE", RELEASE)
+	}
+
+	if p.currentToken.Type == SAVEPOINT {
+		p.nextToken()
+	}
+
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = name
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseSavepointName() (*Identifier, error) {
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected savepoint name", IDENTIFIER)
+	}
+	name := &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+	return name, nil
+}
+
+// parseIfNotExists parses an optional IF NOT EXISTS.
+func (p *Parser) parseIfNotExists() (bool, error) {
+	if p.currentToken.Type != IF {
+		return false, nil
+	}
+	p.nextToken()
+	if !p.expectToken(NOT) {
+		return false, p.expectError("expected NOT after IF", NOT)
+	}
+	if !p.expectToken(EXISTS) {
+		return false, p.expectError("expected EXISTS after IF NOT", EXISTS)
+	}
+	return true, nil
+}
+
+// parseQualifiedName parses [schema.]name, where kind names the object in
+// error messages.
+func (p *Parser) parseQualifiedName(kind string) (*Identifier, *Identifier, error) {
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, nil, p.expectError(fmt.Sprintf("expected %s name", kind), IDENTIFIER)
+	}
+
+	name := &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != DOT {
+		return nil, name, nil
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, nil, p.expectError(fmt.Sprintf("expected %s name after schema", kind), IDENTIFIER)
+	}
+
+	schema := name
+	name = &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	return schema, name, nil
+}
+
+// parseTableElements parses the column definitions of a CREATE TABLE,
+// followed by any table constraints.
+func (p *Parser) parseTableElements(stmt *CreateTableStatement) error {
+	// A table needs at least one column, so the first element is always
+	// parsed as a column definition.
+	for {
+		if len(stmt.Columns) > 0 && p.isTableConstraintKeyword() {
+			constraint, err := p.parseTableConstraint()
+			if err != nil {
+				return err
+			}
+			stmt.Constraints = append(stmt.Constraints, constraint)
+		} else {
+			if len(stmt.Constraints) > 0 {
+				return p.errorf("column definitions must come before table constraints")
+			}
+			col, err := p.parseColumnDef()
+			if err != nil {
+				return err
+			}
+			stmt.Columns = append(stmt.Columns, col)
+		}
+
+		if p.currentToken.Type == COMMA {
+			p.nextToken()
+		} else {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (p *Parser) parseColumnDef() (*ColumnDef, error) {
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected column name", IDENTIFIER)
+	}
+
+	col := &ColumnDef{
+		Name: &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		},
+	}
+	p.nextToken()
+
+	// Type names may span several words, as in "UNSIGNED BIG INT".
+	for p.isDataType() || p.currentToken.Type == IDENTIFIER {
+		if col.Type != "" {
+			col.Type += " "
+		}
+		col.Type += p.currentToken.Value
+		p.nextToken()
+	}
+
+	if col.Type != "" && p.currentToken.Type == LPAREN {
+		args, err := p.parseTypeArgs()
+		if err != nil {
+			return nil, err
+		}
+		col.TypeArgs = args
+	}
+
+	for p.isConstraintKeyword() {
+		constraint, err := p.parseConstraint()
+		if err != nil {
+			return nil, err
+		}
+		col.Constraints = append(col.Constraints, constraint)
+	}
+
+	return col, nil
+}
+
+func (p *Parser) parseInsertStatement() (*InsertStatement, error) {
+	stmt := &InsertStatement{
+		Insert: p.pos(),
+	}
+
+	switch p.currentToken.Type {
+	case REPLACE:
+		// REPLACE INTO is short for INSERT OR REPLACE INTO.
+		stmt.OnConflict = "REPLACE"
+		p.nextToken()
+	case INSERT:
+		p.nextToken()
+		onConflict, err := p.parseOrConflict()
+		if err != nil {
+			return nil, err
+		}
+		stmt.OnConflict = onConflict
+	default:
+		return nil, p.expectError("expected INSERT", INSERT, REPLACE)
+	}
+
+	if p.currentToken.Type == INTO {
+		p.nextToken()
+	}
+
+	schema, table, err := p.parseQualifiedName("table")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Schema = schema
+	stmt.Table = table
+
+	if p.currentToken.Type == LPAREN {
+		p.nextToken()
+		columns, err := p.parseIdentifierList()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Columns = columns
+
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after column list", RPAREN)
+		}
+	}
+
+	switch p.currentToken.Type {
+	case VALUES:
+		p.nextToken()
+		for {
+			if !p.expectToken(LPAREN) {
+				return nil, p.expectError("expected ( after VALUES", LPAREN)
+			}
+
+			row, err := p.parseExpressionList()
+			if err != nil {
+				return nil, err
+			}
+
+			if !p.expectToken(RPAREN) {
+				return nil, p.expectError("expected ) after row values", RPAREN)
+			}
+
+			if len(stmt.Columns) > 0 && len(row) != len(stmt.Columns) {
+				return nil, p.errorf("expected %d values, got %d", len(stmt.Columns), len(row))
+			}
+			if len(stmt.Values) > 0 && len(row) != len(stmt.Values[0]) {
+				return nil, p.errorf("all VALUES must have the same number of terms")
+			}
+			stmt.Values = append(stmt.Values, row)
+
+			if p.currentToken.Type != COMMA {
+				break
+			}
+			p.nextToken()
+		}
+
+	case DEFAULT:
+		p.nextToken()
+		if !p.expectToken(VALUES) {
+			return nil, p.expectError("expected VALUES after DEFAULT", VALUES)
+		}
+		stmt.DefaultValues = true
+
+	case SELECT, WITH:
+		query, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Select = query
+
+	default:
+		return nil, p.expectError("expected VALUES, DEFAULT VALUES or SELECT", VALUES, DEFAULT, SELECT, WITH)
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+// parseOrConflict parses the optional OR ROLLBACK|ABORT|REPLACE|FAIL|IGNORE
+// after INSERT or UPDATE and returns the resolution, or "" if absent.
+func (p *Parser) parseOrConflict() (string, error) {
+	if p.currentToken.Type != OR {
+		return "", nil
+	}
+	p.nextToken()
+	if !p.isConflictResolution() {
+		return "", p.expectError("expected ROLLBACK, ABORT, REPLACE, FAIL or IGNORE after OR", ROLLBACK, ABORT, REPLACE, FAIL, IGNORE)
+	}
+	resolution := p.currentToken.Type.String()
+	p.nextToken()
+	return resolution, nil
+}
+
+func (p *Parser) parseUpdateStatement() (*UpdateStatement, error) {
+	stmt := &UpdateStatement{
+		Update: p.pos(),
+	}
+
+	if !p.expectToken(UPDATE) {
+		return nil, p.expectError("expected UPDATE", UPDATE)
+	}
+
+	onConflict, err := p.parseOrConflict()
+	if err != nil {
+		return nil, err
+	}
+	stmt.OnConflict = onConflict
+
+	schema, table, err := p.parseQualifiedName("table")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Schema = schema
+	stmt.Table = table
+
+	if !p.expectToken(SET) {
+		return nil, p.expectError("expected SET", SET)
+	}
+
+	for {
+		assignment, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Set = append(stmt.Set, assignment)
+
+		if p.currentToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.currentToken.Type == WHERE {
+		p.nextToken()
+		where, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+func (p *Parser) parseAssignment() (*Assignment, error) {
+	if p.currentToken.Type == LPAREN {
+		p.nextToken()
+		columns, err := p.parseIdentifierList()
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after column list", RPAREN)
+		}
+		if !p.expectToken(EQUAL) {
+			return nil, p.expectError("expected = in assignment", EQUAL)
+		}
+		if !p.expectToken(LPAREN) {
+			return nil, p.expectError("expected ( before row values", LPAREN)
+		}
+		values, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after row values", RPAREN)
+		}
+		if len(values) != len(columns) {
+			return nil, p.errorf("expected %d values, got %d", len(columns), len(values))
+		}
+		return &Assignment{Columns: columns, Values: values}, nil
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected column name", IDENTIFIER)
+	}
+	column := &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	if !p.expectToken(EQUAL) {
+		return nil, p.expectError("expected = in assignment", EQUAL)
+	}
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Assignment{Column: column, Value: value}, nil
+}
+
+func (p *Parser) parseDeleteStatement() (*DeleteStatement, error) {
+	stmt := &DeleteStatement{
+		Delete: p.pos(),
+	}
+
+	if !p.expectToken(DELETE) {
+		return nil, p.expectError("expected DELETE", DELETE)
+	}
+
+	if !p.expectToken(FROM) {
+		return nil, p.expectError("expected FROM", FROM)
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected table name", IDENTIFIER)
+	}
+
+	stmt.From = &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == WHERE {
+		p.nextToken()
+		where, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	}
+
+	stmt.End_ = p.end()
+	return stmt, nil
+}
+
+// Operator precedence levels, lowest to highest, following SQLite's
+// operator precedence table.
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precNot
+	precEquality   // = == != <> IS IN LIKE ILIKE GLOB MATCH REGEXP BETWEEN
+	precComparison // < <= > >=
+	precBitwise    // & | << >>
+	precAdditive   // + -
+	precMultiply   // * / %
+	precConcat     // || -> ->>
+	precUnary
+)
+
+func (p *Parser) parseExpression() (Expression, error) {
+	return p.parseBinary(precOr)
+}
+
+// parseBinary parses a chain of binary operators whose precedence is at
+// least minPrec, using precedence climbing.
+func (p *Parser) parseBinary(minPrec int) (Expression, error) {
+	var left Expression
+	var err error
+
+	if p.currentToken.Type == NOT {
+		pos := p.pos()
+		p.nextToken()
+		operand, err := p.parseBinary(precNot)
+		if err != nil {
+			return nil, err
+		}
+		left = &UnaryExpression{
+			Operator: "NOT",
+			Operand:  operand,
+			Pos_:     pos,
+			End_:     p.end(),
+		}
+	} else {
+		left, err = p.parseCollate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		prec := p.binaryPrecedence()
+		if prec == precLowest || prec < minPrec {
+			return left, nil
+		}
+
+		left, err = p.parseBinaryOperator(left, prec)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseBinaryOperator parses the operator at the current token and its
+// right-hand side, combining it with left.
+func (p *Parser) parseBinaryOperator(left Expression, prec int) (Expression, error) {
+	pos := left.Pos()
+	opPos := p.pos()
+
+	switch p.currentToken.Type {
+	case ISNULL:
+		p.nextToken()
+		return &BinaryExpression{Left: left, Operator: "IS", Right: &NullLiteral{Pos_: opPos, End_: p.end()}, Pos_: pos, End_: p.end()}, nil
+	case NOTNULL:
+		p.nextToken()
+		return &BinaryExpression{Left: left, Operator: "IS NOT", Right: &NullLiteral{Pos_: opPos, End_: p.end()}, Pos_: pos, End_: p.end()}, nil
+	}
+
+	operator := p.operatorString()
+	not := false
+
+	switch p.currentToken.Type {
+	case IS:
+		p.nextToken()
+		if p.currentToken.Type == NOT {
+			operator = "IS NOT"
+			p.nextToken()
+		}
+	case NOT:
+		p.nextToken()
+		if p.currentToken.Type == NULL {
+			p.nextToken()
+			return &BinaryExpression{Left: left, Operator: "IS NOT", Right: &NullLiteral{Pos_: opPos, End_: p.end()}, Pos_: pos, End_: p.end()}, nil
+		}
+		not = true
+		operator = "NOT " + p.operatorString()
+		fallthrough
+	default:
+		switch p.currentToken.Type {
+		case BETWEEN:
+			return p.parseBetween(left, not, pos)
+		case IN:
+			return p.parseIn(left, not, pos)
+		}
+		p.nextToken()
+	}
+
+	right, err := p.parseBinary(prec + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &BinaryExpression{
+		Left:     left,
+		Operator: operator,
+		Right:    right,
+		Pos_:     pos,
+	}
+
+	if p.currentToken.Type == ESCAPE && strings.HasSuffix(operator, "LIKE") {
+		p.nextToken()
+		escape, err := p.parseBinary(precBitwise)
+		if err != nil {
+			return nil, err
+		}
+		expr.Escape = escape
+	}
+
+	expr.End_ = p.end()
+	return expr, nil
+}
+
+func (p *Parser) parseBetween(expr Expression, not bool, pos token.Pos) (Expression, error) {
+	if !p.expectToken(BETWEEN) {
+		return nil, p.expectError("expected BETWEEN", BETWEEN)
+	}
+
+	low, err := p.parseBinary(precEquality + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.expectToken(AND) {
+		return nil, p.expectError("expected AND in BETWEEN expression", AND)
+	}
+
+	high, err := p.parseBinary(precEquality + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BetweenExpression{
+		Expr: expr,
+		Not:  not,
+		Low:  low,
+		High: high,
+		Pos_: pos,
+		End_: p.end(),
+	}, nil
+}
+
+func (p *Parser) parseIn(expr Expression, not bool, pos token.Pos) (Expression, error) {
+	if !p.expectToken(IN) {
+		return nil, p.expectError("expected IN", IN)
+	}
+
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( after IN", LPAREN)
+	}
+
+	in := &InExpression{
+		Expr: expr,
+		Not:  not,
+		Pos_: pos,
+	}
+
+	switch p.currentToken.Type {
+	case SELECT, WITH:
+		query, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		in.Query = query
+	case RPAREN:
+		// SQLite allows an empty list: x IN ()
+	default:
+		values, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		in.Values = values
+	}
+
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after IN list", RPAREN)
+	}
+
+	in.End_ = p.end()
+	return in, nil
+}
+
+// parseCaseExpression parses both the simple form "CASE x WHEN 1 THEN ..."
+// and the searched form "CASE WHEN cond THEN ...".
+func (p *Parser) parseCaseExpression() (Expression, error) {
+	expr := &CaseExpression{
+		Pos_: p.pos(),
+	}
+
+	if !p.expectToken(CASE) {
+		return nil, p.expectError("expected CASE", CASE)
+	}
+
+	if p.currentToken.Type != WHEN {
+		operand, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		expr.Operand = operand
+	}
+
+	for p.currentToken.Type == WHEN {
+		p.nextToken()
+		condition, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.expectToken(THEN) {
+			return nil, p.expectError("expected THEN after WHEN condition", THEN)
+		}
+
+		result, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		expr.Whens = append(expr.Whens, &WhenClause{
+			Condition: condition,
+			Result:    result,
+		})
+	}
+
+	if len(expr.Whens) == 0 {
+		return nil, p.expectError("expected WHEN in CASE expression", WHEN)
+	}
+
+	if p.currentToken.Type == ELSE {
+		p.nextToken()
+		elseExpr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		expr.Else = elseExpr
+	}
+
+	if !p.expectToken(END) {
+		return nil, p.expectError("expected END after CASE expression", END)
+	}
+
+	expr.End_ = p.end()
+	return expr, nil
+}
+
+// parseCollate parses a unary expression followed by any number of
+// COLLATE name and ::type suffixes.
+func (p *Parser) parseCollate() (Expression, error) {
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == COLLATE || p.currentToken.Type == DOUBLE_COLON {
+		if p.currentToken.Type == DOUBLE_COLON {
+			expr, err = p.parseCast(expr)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		p.nextToken()
+		if p.currentToken.Type != IDENTIFIER {
+			return nil, p.expectError("expected collation name after COLLATE", IDENTIFIER)
+		}
+		expr = &CollateExpression{
+			Expr:      expr,
+			Collation: p.currentToken.Value,
+			Pos_:      expr.Pos(),
+			End_:      p.tokenEnd(),
+		}
+		p.nextToken()
+	}
+
+	return expr, nil
+}
+
+// parseCast parses the ::type suffix of a cast applied to expr.
+func (p *Parser) parseCast(expr Expression) (Expression, error) {
+	p.nextToken()
+	if !p.isDataType() && p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected type name after ::", IDENTIFIER)
+	}
+
+	cast := &CastExpression{
+		Expr: expr,
+		Type: p.currentToken.Value,
+		Pos_: expr.Pos(),
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == LPAREN {
+		args, err := p.parseTypeArgs()
+		if err != nil {
+			return nil, err
+		}
+		cast.TypeArgs = args
+	}
+
+	cast.End_ = p.end()
+	return cast, nil
+}
+
+// parseTypeArgs parses the parenthesized numbers after a type name, as in
+// VARCHAR(255) or DECIMAL(10, 2).
+func (p *Parser) parseTypeArgs() ([]string, error) {
+	var args []string
+	p.nextToken()
+	for {
+		arg := ""
+		if p.currentToken.Type == PLUS || p.currentToken.Type == MINUS {
+			arg = p.currentToken.Value
+			p.nextToken()
+		}
+		if p.currentToken.Type != NUMBER {
+			return nil, p.expectError("expected number in type arguments", NUMBER)
+		}
+		args = append(args, arg+p.currentToken.Value)
+		p.nextToken()
+
+		if p.currentToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after type arguments", RPAREN)
+	}
+	return args, nil
+}
+
+func (p *Parser) parseUnary() (Expression, error) {
+	switch p.currentToken.Type {
+	case MINUS, PLUS, TILDE:
+		operator := p.currentToken.Value
+		pos := p.pos()
+		p.nextToken()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpression{
+			Operator: operator,
+			Operand:  operand,
+			Pos_:     pos,
+			End_:     p.end(),
+		}, nil
+	default:
+		return p.parsePrimary()
+	}
+}
+
+// binaryPrecedence returns the precedence of the current token when used
+// as a binary operator, or precLowest if it is not one.
+func (p *Parser) binaryPrecedence() int {
+	switch p.currentToken.Type {
+	case OR:
+		return precOr
+	case AND:
+		return precAnd
+	case EQUAL, NOT_EQUAL, NOT_EQUAL2, IS, ISNULL, NOTNULL, IN, LIKE, ILIKE, GLOB, MATCH, REGEXP, BETWEEN:
+		return precEquality
+	case NOT:
+		switch p.peekToken.Type {
+		case NULL, IN, LIKE, ILIKE, GLOB, MATCH, REGEXP, BETWEEN:
+			return precEquality
+		}
+		return precLowest
+	case LESS, LESS_EQUAL, GREATER, GREATER_EQUAL:
+		return precComparison
+	case AMPERSAND, PIPE, LEFT_SHIFT, RIGHT_SHIFT:
+		return precBitwise
+	case PLUS, MINUS:
+		return precAdditive
+	case ASTERISK, DIVIDE, MODULO:
+		return precMultiply
+	case CONCAT, ARROW, LONG_ARROW:
+		return precConcat
+	default:
+		return precLowest
+	}
+}
+
+// operatorString returns the canonical spelling of the current operator
+// token, upper-casing keyword operators such as AND or LIKE.
+func (p *Parser) operatorString() string {
+	if p.currentToken.Type.IsKeyword() {
+		return strings.ToUpper(p.currentToken.Value)
+	}
+	return p.currentToken.Value
+}
+
+func (p *Parser) parsePrimary() (Expression, error) {
+	switch p.currentToken.Type {
+	case IDENTIFIER, COUNT, SUM, AVG, MIN, MAX:
+		if p.peekToken.Type == LPAREN {
+			return p.parseFunctionCall()
+		}
+		if p.currentToken.Type == IDENTIFIER && p.peekToken.Type == DOT {
+			return p.parseQualifiedIdentifier()
+		}
+
+		ident := &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}
+		p.nextToken()
+		return ident, nil
+
+	case STRING:
+		value := p.currentToken.Value
+		pos := p.pos()
+		p.nextToken()
+		return &StringLiteral{
+			Value: value,
+			Pos_:  pos,
+			End_:  p.end(),
+		}, nil
+
+	case NUMBER:
+		value := p.currentToken.Value
+		pos := p.pos()
+		p.nextToken()
+		return &NumberLiteral{
+			Value: value,
+			Pos_:  pos,
+			End_:  p.end(),
+		}, nil
+
+	case NULL:
+		pos := p.pos()
+		p.nextToken()
+		return &NullLiteral{Pos_: pos, End_: p.end()}, nil
+
+	case LPAREN:
+		pos := p.pos()
+		p.nextToken()
+		if p.currentToken.Type == SELECT || p.currentToken.Type == WITH {
+			query, err := p.parseQuery()
+			if err != nil {
+				return nil, err
+			}
+			if !p.expectToken(RPAREN) {
+				return nil, p.expectError("expected ) after subquery", RPAREN)
+			}
+			return &SubqueryExpression{
+				Query: query,
+				Pos_:  pos,
+				End_:  p.end(),
+			}, nil
+		}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected )", RPAREN)
+		}
+		return &ParenExpression{
+			Expr: expr,
+			Pos_: pos,
+			End_: p.end(),
+		}, nil
+
+	case CASE:
+		return p.parseCaseExpression()
+
+	case EXISTS:
+		pos := p.pos()
+		p.nextToken()
+		if !p.expectToken(LPAREN) {
+			return nil, p.expectError("expected ( after EXISTS", LPAREN)
+		}
+		query, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after subquery", RPAREN)
+		}
+		return &ExistsExpression{
+			Query: query,
+			Pos_:  pos,
+			End_:  p.end(),
+		}, nil
+
+	case TRUE, FALSE:
+		value := p.currentToken.Type == TRUE
+		pos := p.pos()
+		p.nextToken()
+		return &BooleanLiteral{
+			Value: value,
+			Pos_:  pos,
+			End_:  p.end(),
+		}, nil
+
+	case BLOB_LITERAL:
+		value := p.currentToken.Value
+		pos := p.pos()
+		p.nextToken()
+		return &BlobLiteral{
+			Value: value,
+			Pos_:  pos,
+			End_:  p.end(),
+		}, nil
+
+	case PARAMETER, NAMED_PARAMETER, NUMBERED_PARAMETER:
+		return p.parseParameter()
+
+	default:
+		if p.isFunctionKeyword() && p.peekToken.Type == LPAREN {
+			return p.parseFunctionCall()
+		}
+		err := p.errorf("unexpected token: %s", p.currentToken.Type)
+		if !p.recover {
+			return nil, err
+		}
+		// Skip to a token that can follow an expression. If the operand is
+		// missing altogether, nothing is skipped and the node is empty.
+		pos := p.pos()
+		for !p.currentToken.Type.IsKeyword() && !p.isExpressionEnd() {
+			p.nextToken()
+		}
+		to := p.end()
+		if to < pos {
+			to = pos
+		}
+		return &BadExpression{From: pos, To: to}, nil
+	}
+}
+
+// isExpressionEnd reports whether the current token is punctuation that
+// ends an expression.
+func (p *Parser) isExpressionEnd() bool {
+	switch p.currentToken.Type {
+	case COMMA, RPAREN, SEMICOLON, EOF:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseParameter parses a ?, ?NNN or named parameter and numbers it.
+func (p *Parser) parseParameter() (*Parameter, error) {
+	param := &Parameter{
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+
+	switch p.currentToken.Type {
+	case PARAMETER:
+		p.maxParam++
+		param.Index = p.maxParam
+	case NUMBERED_PARAMETER:
+		index, err := strconv.Atoi(p.currentToken.Value[1:])
+		if err != nil || index < 1 {
+			return nil, p.errorf("invalid parameter number %s", p.currentToken.Value)
+		}
+		param.Name = p.currentToken.Value
+		param.Index = index
+		if index > p.maxParam {
+			p.maxParam = index
+		}
+	default:
+		param.Name = p.currentToken.Value
+		if index, ok := p.params[param.Name]; ok {
+			param.Index = index
+		} else {
+			p.maxParam++
+			param.Index = p.maxParam
+			p.params[param.Name] = param.Index
+		}
+	}
+
+	p.nextToken()
+	return param, nil
+}
+
+// parseFromClause parses a table source followed by any number of joins.
+func (p *Parser) parseFromClause() (TableExpr, error) {
+	left, err := p.parseTableSource()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		join := &JoinExpr{Left: left}
+
+		if p.currentToken.Type == COMMA {
+			p.nextToken()
+			join.Type = ","
+		} else {
+			if p.currentToken.Type == NATURAL {
+				join.Natural = true
+				p.nextToken()
+			}
+
+			switch p.currentToken.Type {
+			case LEFT, RIGHT, FULL:
+				join.Type = p.currentToken.Type.String()
+				p.nextToken()
+				if p.currentToken.Type == OUTER {
+					p.nextToken()
+				}
+			case INNER, CROSS:
+				join.Type = p.currentToken.Type.String()
+				p.nextToken()
+			case JOIN:
+				join.Type = "INNER"
+			default:
+				if join.Natural {
+					return nil, p.expectError("expected JOIN after NATURAL", JOIN)
+				}
+				return left, nil
+			}
+
+			if !p.expectToken(JOIN) {
+				return nil, p.expectError("expected JOIN", JOIN)
+			}
+		}
+
+		right, err := p.parseTableSource()
+		if err != nil {
+			return nil, err
+		}
+		join.Right = right
+
+		switch p.currentToken.Type {
+		case ON:
+			p.nextToken()
+			on, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			join.On = on
+		case USING:
+			p.nextToken()
+			if !p.expectToken(LPAREN) {
+				return nil, p.expectError("expected ( after USING", LPAREN)
+			}
+			using, err := p.parseIdentifierList()
+			if err != nil {
+				return nil, err
+			}
+			if !p.expectToken(RPAREN) {
+				return nil, p.expectError("expected ) after USING columns", RPAREN)
+			}
+			join.Using = using
+		}
+
+		if join.Natural && (join.On != nil || join.Using != nil) {
+			return nil, p.errorf("a NATURAL join cannot have an ON or USING clause")
+		}
+
+		join.End_ = p.end()
+		left = join
+	}
+}
+
+// parseFunctionCall parses name(args), including COUNT(*), DISTINCT
+// arguments and an ORDER BY inside aggregate calls.
+func (p *Parser) parseFunctionCall() (Expression, error) {
+	call := &FunctionCall{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+	}
+	p.nextToken()
+
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( after function name", LPAREN)
+	}
+
+	switch p.currentToken.Type {
+	case RPAREN:
+	case ASTERISK:
+		call.Args = []Expression{&Identifier{
+			Name: "*",
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}}
+		p.nextToken()
+	default:
+		if p.currentToken.Type == DISTINCT {
+			call.Distinct = true
+			p.nextToken()
+		}
+
+		args, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = args
+
+		if p.currentToken.Type == ORDER {
+			p.nextToken()
+			if !p.expectToken(BY) {
+				return nil, p.expectError("expected BY after ORDER", BY)
+			}
+			orderBy, err := p.parseOrderBy()
+			if err != nil {
+				return nil, err
+			}
+			call.OrderBy = orderBy
+		}
+	}
+
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after function arguments", RPAREN)
+	}
+
+	if err := p.parseFilterAndOver(call); err != nil {
+		return nil, err
+	}
+
+	call.End_ = p.end()
+	return call, nil
+}
+
+// parseQualifiedIdentifier parses table.column or schema.table.column,
+// where the column may be * as in "SELECT t.* FROM t".
+func (p *Parser) parseQualifiedIdentifier() (Expression, error) {
+	parts := []*Identifier{}
+
+	for {
+		switch {
+		case p.currentToken.Type == IDENTIFIER:
+		case p.currentToken.Type == ASTERISK && len(parts) > 0:
+		default:
+			return nil, p.expectError("expected identifier after .", IDENTIFIER)
+		}
+
+		parts = append(parts, &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		})
+		isStar := p.currentToken.Type == ASTERISK
+		p.nextToken()
+
+		if isStar || p.currentToken.Type != DOT {
+			break
+		}
+		p.nextToken()
+	}
+
+	switch len(parts) {
+	case 2:
+		return &QualifiedIdentifier{Table: parts[0], Column: parts[1], End_: p.end()}, nil
+	case 3:
+		return &QualifiedIdentifier{Schema: parts[0], Table: parts[1], Column: parts[2], End_: p.end()}, nil
+	default:
+		return nil, p.errorf("too many qualifiers in %s", parts[0].Name)
+	}
+}
+
+// parseFilterAndOver parses the optional FILTER (WHERE ...) and OVER
+// clauses that may follow a function call's argument list.
+func (p *Parser) parseFilterAndOver(call *FunctionCall) error {
+	if p.currentToken.Type == FILTER {
+		p.nextToken()
+		if !p.expectToken(LPAREN) {
+			return p.expectError("expected ( after FILTER", LPAREN)
+		}
+		if !p.expectToken(WHERE) {
+			return p.expectError("expected WHERE in FILTER clause", WHERE)
+		}
+		filter, err := p.parseExpression()
+		if err != nil {
+			return err
+		}
+		if !p.expectToken(RPAREN) {
+			return p.expectError("expected ) after FILTER clause", RPAREN)
+		}
+		call.Filter = filter
+	}
+
+	if p.currentToken.Type != OVER {
+		return nil
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == IDENTIFIER {
+		call.Over = &WindowSpec{
+			Name: &Identifier{
+				Name: p.currentToken.Value,
+				Pos_: p.pos(),
+				End_: p.tokenEnd(),
+			},
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}
+		p.nextToken()
+		return nil
+	}
+
+	spec, err := p.parseWindowSpec()
+	if err != nil {
+		return err
+	}
+	call.Over = spec
+	return nil
+}
+
+// parseNamedWindow parses "name AS (window-spec)" from a WINDOW clause.
+func (p *Parser) parseNamedWindow() (*NamedWindow, error) {
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected window name", IDENTIFIER)
+	}
+
+	window := &NamedWindow{
+		Name: &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		},
+	}
+	p.nextToken()
+
+	if !p.expectToken(AS) {
+		return nil, p.expectError("expected AS after window name", AS)
+	}
+
+	spec, err := p.parseWindowSpec()
+	if err != nil {
+		return nil, err
+	}
+	window.Spec = spec
+
+	return window, nil
+}
+
+// parseWindowSpec parses a parenthesised window definition:
+// ([base] [PARTITION BY ...] [ORDER BY ...] [frame]).
+func (p *Parser) parseWindowSpec() (*WindowSpec, error) {
+	spec := &WindowSpec{
+		Pos_: p.pos(),
+	}
+
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( to start window definition", LPAREN)
+	}
+
+	if p.currentToken.Type == IDENTIFIER {
+		spec.Name = &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}
+		p.nextToken()
+	}
+
+	if p.currentToken.Type == PARTITION {
+		p.nextToken()
+		if !p.expectToken(BY) {
+			return nil, p.expectError("expected BY after PARTITION", BY)
+		}
+		partitionBy, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		spec.PartitionBy = partitionBy
+	}
+
+	if p.currentToken.Type == ORDER {
+		p.nextToken()
+		if !p.expectToken(BY) {
+			return nil, p.expectError("expected BY after ORDER", BY)
+		}
+		orderBy, err := p.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+		spec.OrderBy = orderBy
+	}
+
+	switch p.currentToken.Type {
+	case ROWS, RANGE, GROUPS:
+		frame, err := p.parseWindowFrame()
+		if err != nil {
+			return nil, err
+		}
+		spec.Frame = frame
+	}
+
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) to end window definition", RPAREN)
+	}
+
+	spec.End_ = p.end()
+	return spec, nil
+}
+
+func (p *Parser) parseWindowFrame() (*WindowFrame, error) {
+	frame := &WindowFrame{
+		Type: p.currentToken.Type.String(),
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == BETWEEN {
+		p.nextToken()
+		start, err := p.parseFrameBound()
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(AND) {
+			return nil, p.expectError("expected AND in frame BETWEEN", AND)
+		}
+		end, err := p.parseFrameBound()
+		if err != nil {
+			return nil, err
+		}
+		frame.Start = start
+		frame.End = end
+	} else {
+		start, err := p.parseFrameBound()
+		if err != nil {
+			return nil, err
+		}
+		frame.Start = start
+	}
+
+	if p.currentToken.Type == EXCLUDE {
+		p.nextToken()
+		switch p.currentToken.Type {
+		case NO:
+			p.nextToken()
+			if !p.expectToken(OTHERS) {
+				return nil, p.expectError("expected OTHERS after EXCLUDE NO", OTHERS)
+			}
+			frame.Exclude = "NO OTHERS"
+		case CURRENT:
+			p.nextToken()
+			if !p.expectToken(ROW) {
+				return nil, p.expectError("expected ROW after EXCLUDE CURRENT", ROW)
+			}
+			frame.Exclude = "CURRENT ROW"
+		case GROUP, TIES:
+			frame.Exclude = p.currentToken.Type.String()
+			p.nextToken()
+		default:
+			return nil, p.errorf("expected NO OTHERS, CURRENT ROW, GROUP or TIES after EXCLUDE")
+		}
+	}
+
+	return frame, nil
+}
+
+func (p *Parser) parseFrameBound() (*FrameBound, error) {
+	switch p.currentToken.Type {
+	case UNBOUNDED:
+		p.nextToken()
+		switch p.currentToken.Type {
+		case PRECEDING:
+			p.nextToken()
+			return &FrameBound{Type: "UNBOUNDED PRECEDING"}, nil
+		case FOLLOWING:
+			p.nextToken()
+			return &FrameBound{Type: "UNBOUNDED FOLLOWING"}, nil
+		default:
+			return nil, p.expectError("expected PRECEDING or FOLLOWING after UNBOUNDED", PRECEDING, FOLLOWING)
+		}
+	case CURRENT:
+		p.nextToken()
+		if !p.expectToken(ROW) {
+			return nil, p.expectError("expected ROW after CURRENT", ROW)
+		}
+		return &FrameBound{Type: "CURRENT ROW"}, nil
+	}
+
+	offset, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.currentToken.Type {
+	case PRECEDING, FOLLOWING:
+		bound := &FrameBound{Type: p.currentToken.Type.String(), Offset: offset}
+		p.nextToken()
+		return bound, nil
+	default:
+		return nil, p.expectError("expected PRECEDING or FOLLOWING after frame offset", PRECEDING, FOLLOWING)
+	}
+}
+
+// parseTableSource parses a single table source: a table name, a derived
+// table "(SELECT ...) AS t", or a parenthesised join.
+func (p *Parser) parseTableSource() (TableExpr, error) {
+	if p.currentToken.Type != LPAREN {
+		return p.parseTableRef()
+	}
+
+	pos := p.pos()
+	p.nextToken()
+
+	if p.currentToken.Type != SELECT && p.currentToken.Type != WITH {
+		inner, err := p.parseFromClause()
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectToken(RPAREN) {
+			return nil, p.expectError("expected ) after join", RPAREN)
+		}
+		return &ParenTableExpr{Expr: inner, Pos_: pos, End_: p.end()}, nil
+	}
+
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after subquery", RPAREN)
+	}
+
+	derived := &DerivedTable{
+		Query: query,
+		Pos_:  pos,
+	}
+
+	alias, err := p.parseTableAlias()
+	if err != nil {
+		return nil, err
+	}
+	derived.Alias = alias
+
+	derived.End_ = p.end()
+	return derived, nil
+}
+
+func (p *Parser) parseTableRef() (*TableRef, error) {
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected table name", IDENTIFIER)
+	}
+
+	table := &TableRef{
+		Name: &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		},
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == DOT {
+		p.nextToken()
+		if p.currentToken.Type != IDENTIFIER {
+			return nil, p.expectError("expected table name after schema", IDENTIFIER)
+		}
+		table.Schema = table.Name
+		table.Name = &Identifier{
+			Name: p.currentToken.Value,
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		}
+		p.nextToken()
+	}
+
+	alias, err := p.parseTableAlias()
+	if err != nil {
+		return nil, err
+	}
+	table.Alias = alias
+
+	table.End_ = p.end()
+	return table, nil
+}
 
-	default:
-		return nil, fmt.Errorf("unexpected token: %s", p.currentToken.Type)
+// parseTableAlias parses an optional "[AS] alias" after a table source.
+func (p *Parser) parseTableAlias() (*Identifier, error) {
+	if p.currentToken.Type == AS {
+		p.nextToken()
+		if p.currentToken.Type != IDENTIFIER {
+			return nil, p.expectError("expected alias after AS", IDENTIFIER)
+		}
 	}
-}
 
-func (p *Parser) parseTableRef() (*TableRef, error) {
 	if p.currentToken.Type != IDENTIFIER {
-		return nil, fmt.Errorf("expected table name")
+		return nil, nil
 	}
 
-	table := &TableRef{
-		Name: &Identifier{
-			Name: p.currentToken.Value,
-			Pos_: token.Pos(p.currentToken.Col),
-		},
+	alias := &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
 	}
 	p.nextToken()
 
-	if p.currentToken.Type == AS {
-		p.nextToken()
+	return alias, nil
+}
+
+func (p *Parser) parseIdentifierList() ([]*Identifier, error) {
+	var idents []*Identifier
+
+	for {
 		if p.currentToken.Type != IDENTIFIER {
-			return nil, fmt.Errorf("expected alias after AS")
+			return nil, p.expectError("expected column name", IDENTIFIER)
 		}
-		table.Alias = &Identifier{
+		idents = append(idents, &Identifier{
 			Name: p.currentToken.Value,
-			Pos_: token.Pos(p.currentToken.Col),
+			Pos_: p.pos(),
+			End_: p.tokenEnd(),
+		})
+		p.nextToken()
+
+		if p.currentToken.Type != COMMA {
+			break
 		}
 		p.nextToken()
 	}
 
-	return table, nil
+	return idents, nil
+}
+
+// parseParenIdentifierList parses a parenthesised, comma-separated list
+// of column names.
+func (p *Parser) parseParenIdentifierList() ([]*Identifier, error) {
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( before column list", LPAREN)
+	}
+	idents, err := p.parseIdentifierList()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after column list", RPAREN)
+	}
+	return idents, nil
+}
+
+func (p *Parser) parseExpressionList() ([]Expression, error) {
+	var exprs []Expression
+
+	for {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if p.currentToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+
+	return exprs, nil
+}
+
+// parseIndexedColumns parses the parenthesised columns of an index or a
+// PRIMARY KEY or UNIQUE table constraint, each with an optional COLLATE
+// and direction.
+func (p *Parser) parseIndexedColumns() ([]OrderByItem, error) {
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( before indexed columns", LPAREN)
+	}
+	columns, err := p.parseOrderBy()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after indexed columns", RPAREN)
+	}
+	return columns, nil
 }
 
 func (p *Parser) parseOrderBy() ([]OrderByItem, error) {
@@ -470,8 +3077,8 @@ func (p *Parser) parseOrderBy() ([]OrderByItem, error) {
 
 		direction := "ASC"
 		if p.currentToken.Type == IDENTIFIER {
-			if p.currentToken.Value == "DESC" || p.currentToken.Value == "ASC" {
-				direction = p.currentToken.Value
+			if dir := strings.ToUpper(p.currentToken.Value); dir == "ASC" || dir == "DESC" {
+				direction = dir
 				p.nextToken()
 			}
 		}
@@ -500,6 +3107,18 @@ func (p *Parser) parseLimitClause() (*LimitClause, error) {
 		Count: count,
 	}
 
+	// LIMIT offset, count
+	if p.currentToken.Type == COMMA && p.dialect.LimitComma {
+		p.nextToken()
+		count, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		clause.Offset = clause.Count
+		clause.Count = count
+		return clause, nil
+	}
+
 	if p.currentToken.Type == OFFSET {
 		p.nextToken()
 		offset, err := p.parseExpression()
@@ -512,25 +3131,338 @@ func (p *Parser) parseLimitClause() (*LimitClause, error) {
 	return clause, nil
 }
 
+// parseConstraint parses a column constraint, optionally named with
+// CONSTRAINT name.
 func (p *Parser) parseConstraint() (Constraint, error) {
+	pos := p.pos()
+	name, err := p.parseConstraintName()
+	if err != nil {
+		return nil, err
+	}
+
 	switch p.currentToken.Type {
 	case PRIMARY:
-		pos := token.Pos(p.currentToken.Col)
 		p.nextToken()
 		if !p.expectToken(KEY) {
-			return nil, fmt.Errorf("expected KEY after PRIMARY")
+			return nil, p.expectError("expected KEY after PRIMARY", KEY)
+		}
+		constraint := &PrimaryKeyConstraint{Name: name, Pos_: pos}
+		if p.currentToken.Type == IDENTIFIER {
+			if dir := strings.ToUpper(p.currentToken.Value); dir == "ASC" || dir == "DESC" {
+				constraint.Order = dir
+				p.nextToken()
+			}
+		}
+		constraint.OnConflict, err = p.parseConflictClause()
+		if err != nil {
+			return nil, err
 		}
-		return &PrimaryKeyConstraint{Pos_: pos}, nil
+		if p.currentToken.Type == AUTOINCREMENT {
+			constraint.Autoincrement = true
+			p.nextToken()
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
 	case NOT:
-		pos := token.Pos(p.currentToken.Col)
 		p.nextToken()
 		if !p.expectToken(NULL) {
-			return nil, fmt.Errorf("expected NULL after NOT")
+			return nil, p.expectError("expected NULL after NOT", NULL)
+		}
+		constraint := &NotNullConstraint{Name: name, Pos_: pos}
+		constraint.OnConflict, err = p.parseConflictClause()
+		if err != nil {
+			return nil, err
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
+	case UNIQUE:
+		p.nextToken()
+		constraint := &UniqueConstraint{Name: name, Pos_: pos}
+		constraint.OnConflict, err = p.parseConflictClause()
+		if err != nil {
+			return nil, err
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
+	case CHECK:
+		return p.parseCheckConstraint(name)
+	case DEFAULT:
+		p.nextToken()
+		// Only a literal, a signed number or a parenthesised expression may
+		// follow DEFAULT, so "DEFAULT 0 NOT NULL" is not read as "0 NOT NULL".
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &DefaultConstraint{Name: name, Value: value, Pos_: pos, End_: p.end()}, nil
+	case COLLATE:
+		p.nextToken()
+		if p.currentToken.Type != IDENTIFIER {
+			return nil, p.expectError("expected collation name after COLLATE", IDENTIFIER)
+		}
+		collation := p.currentToken.Value
+		p.nextToken()
+		return &CollateConstraint{Name: name, Collation: collation, Pos_: pos, End_: p.end()}, nil
+	case REFERENCES:
+		constraint := &ForeignKeyConstraint{Name: name, Pos_: pos}
+		if err := p.parseForeignKeyClause(constraint); err != nil {
+			return nil, err
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
+	default:
+		return nil, p.errorf("unknown constraint: %s", p.currentToken.Type)
+	}
+}
+
+// parseTableConstraint parses a table-level PRIMARY KEY, UNIQUE, CHECK or
+// FOREIGN KEY constraint.
+func (p *Parser) parseTableConstraint() (Constraint, error) {
+	pos := p.pos()
+	name, err := p.parseConstraintName()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.currentToken.Type {
+	case PRIMARY:
+		p.nextToken()
+		if !p.expectToken(KEY) {
+			return nil, p.expectError("expected KEY after PRIMARY", KEY)
+		}
+		columns, err := p.parseIndexedColumns()
+		if err != nil {
+			return nil, err
+		}
+		constraint := &PrimaryKeyConstraint{Name: name, Columns: columns, Pos_: pos}
+		constraint.OnConflict, err = p.parseConflictClause()
+		if err != nil {
+			return nil, err
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
+	case UNIQUE:
+		p.nextToken()
+		columns, err := p.parseIndexedColumns()
+		if err != nil {
+			return nil, err
+		}
+		constraint := &UniqueConstraint{Name: name, Columns: columns, Pos_: pos}
+		constraint.OnConflict, err = p.parseConflictClause()
+		if err != nil {
+			return nil, err
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
+	case CHECK:
+		return p.parseCheckConstraint(name)
+	case FOREIGN:
+		p.nextToken()
+		if !p.expectToken(KEY) {
+			return nil, p.expectError("expected KEY after FOREIGN", KEY)
+		}
+		columns, err := p.parseParenIdentifierList()
+		if err != nil {
+			return nil, err
+		}
+		constraint := &ForeignKeyConstraint{Name: name, Columns: columns, Pos_: pos}
+		if err := p.parseForeignKeyClause(constraint); err != nil {
+			return nil, err
+		}
+		constraint.End_ = p.end()
+		return constraint, nil
+	default:
+		return nil, p.errorf("unknown table constraint: %s", p.currentToken.Type)
+	}
+}
+
+func (p *Parser) parseConstraintName() (*Identifier, error) {
+	if p.currentToken.Type != CONSTRAINT {
+		return nil, nil
+	}
+	p.nextToken()
+
+	if p.currentToken.Type != IDENTIFIER {
+		return nil, p.expectError("expected constraint name", IDENTIFIER)
+	}
+	name := &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	return name, nil
+}
+
+func (p *Parser) parseCheckConstraint(name *Identifier) (Constraint, error) {
+	pos := p.pos()
+
+	if !p.expectToken(CHECK) {
+		return nil, p.expectError("expected CHECK", CHECK)
+	}
+	if !p.expectToken(LPAREN) {
+		return nil, p.expectError("expected ( after CHECK", LPAREN)
+	}
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectToken(RPAREN) {
+		return nil, p.expectError("expected ) after CHECK expression", RPAREN)
+	}
+
+	return &CheckConstraint{Name: name, Expr: expr, Pos_: pos, End_: p.end()}, nil
+}
+
+// parseForeignKeyClause parses REFERENCES table [(columns)] followed by
+// any ON DELETE / ON UPDATE actions.
+func (p *Parser) parseForeignKeyClause(constraint *ForeignKeyConstraint) error {
+	if !p.expectToken(REFERENCES) {
+		return p.expectError("expected REFERENCES", REFERENCES)
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		return p.expectError("expected table name after REFERENCES", IDENTIFIER)
+	}
+	constraint.Table = &Identifier{
+		Name: p.currentToken.Value,
+		Pos_: p.pos(),
+		End_: p.tokenEnd(),
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == LPAREN {
+		columns, err := p.parseParenIdentifierList()
+		if err != nil {
+			return err
+		}
+		constraint.RefColumns = columns
+	}
+
+	for p.currentToken.Type == ON {
+		p.nextToken()
+
+		event := p.currentToken.Type
+		if event != DELETE && event != UPDATE {
+			return p.expectError("expected DELETE or UPDATE after ON", DELETE, UPDATE)
+		}
+		p.nextToken()
+
+		action, err := p.parseForeignKeyAction()
+		if err != nil {
+			return err
+		}
+
+		if event == DELETE {
+			constraint.OnDelete = action
+		} else {
+			constraint.OnUpdate = action
+		}
+	}
+
+	// [NOT] DEFERRABLE [INITIALLY DEFERRED | INITIALLY IMMEDIATE]
+	if p.currentToken.Type == NOT && p.peekToken.Type == DEFERRABLE {
+		p.nextToken()
+		constraint.Deferrable = "NOT DEFERRABLE"
+	} else if p.currentToken.Type == DEFERRABLE {
+		constraint.Deferrable = "DEFERRABLE"
+	}
+	if constraint.Deferrable == "" {
+		return nil
+	}
+	p.nextToken()
+
+	if p.currentToken.Type == INITIALLY {
+		p.nextToken()
+		if p.currentToken.Type != DEFERRED && p.currentToken.Type != IMMEDIATE {
+			return p.expectError("expected DEFERRED or IMMEDIATE after INITIALLY", DEFERRED, IMMEDIATE)
+		}
+		constraint.Initially = p.currentToken.Type.String()
+		p.nextToken()
+	}
+
+	return nil
+}
+
+func (p *Parser) parseForeignKeyAction() (string, error) {
+	switch p.currentToken.Type {
+	case CASCADE, RESTRICT:
+		action := p.currentToken.Type.String()
+		p.nextToken()
+		return action, nil
+	case SET:
+		p.nextToken()
+		switch p.currentToken.Type {
+		case NULL:
+			p.nextToken()
+			return "SET NULL", nil
+		case DEFAULT:
+			p.nextToken()
+			return "SET DEFAULT", nil
+		}
+		return "", p.expectError("expected NULL or DEFAULT after SET", NULL, DEFAULT)
+	case NO:
+		p.nextToken()
+		if !p.expectToken(ACTION) {
+			return "", p.expectError("expected ACTION after NO", ACTION)
 		}
-		return &NotNullConstraint{Pos_: pos}, nil
+		return "NO ACTION", nil
 	default:
-		return nil, fmt.Errorf("unknown constraint: %s", p.currentToken.Type)
+		return "", p.errorf("expected foreign key action")
+	}
+}
+
+// parseConflictClause parses an optional ON CONFLICT resolution.
+func (p *Parser) parseConflictClause() (string, error) {
+	if p.currentToken.Type != ON || p.peekToken.Type != CONFLICT {
+		return "", nil
+	}
+	p.nextToken()
+	p.nextToken()
+
+	if !p.isConflictResolution() {
+		return "", p.expectError("expected ROLLBACK, ABORT, REPLACE, FAIL or IGNORE after ON CONFLICT", ROLLBACK, ABORT, REPLACE, FAIL, IGNORE)
+	}
+	resolution := p.currentToken.Type.String()
+	p.nextToken()
+
+	return resolution, nil
+}
+
+// errorf records and returns a ParseError at the current token.
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return p.expectError(fmt.Sprintf(format, args...))
+}
+
+// expectError records and returns a ParseError at the current token, noting
+// the token types that would have been accepted there.
+func (p *Parser) expectError(msg string, expected ...TokenType) error {
+	// The lexer knows better what is wrong with an ILLEGAL token.
+	if p.currentToken.Type == ILLEGAL {
+		for i := len(p.lexer.errors) - 1; i >= 0; i-- {
+			if p.lexer.errors[i].Offset == p.currentToken.Offset {
+				msg = p.lexer.errors[i].Msg
+				break
+			}
+		}
+	}
+
+	err := &ParseError{
+		Line:     p.currentToken.Line,
+		Col:      p.currentToken.Col,
+		Offset:   p.currentToken.Offset,
+		Token:    p.currentToken,
+		Expected: expected,
+		Msg:      msg,
+	}
+
+	// When recovering, a follow-on error at the same token adds nothing.
+	if n := len(p.errors); p.recover && n > 0 && p.errors[n-1].Offset == err.Offset {
+		return err
 	}
+	p.errors.Add(err)
+	return err
 }
 
 func (p *Parser) expectToken(expected TokenType) bool {
@@ -550,25 +3482,93 @@ func (p *Parser) isDataType() bool {
 	}
 }
 
+// isFunctionKeyword reports whether the current token is a keyword that
+// doubles as a function name, such as REPLACE, LIKE or GLOB.
+func (p *Parser) isFunctionKeyword() bool {
+	switch p.currentToken.Type {
+	case REPLACE, LIKE, GLOB, MATCH, REGEXP, LEFT, RIGHT, CHAR, DATETIME:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) isConstraintKeyword() bool {
 	switch p.currentToken.Type {
-	case PRIMARY, NOT, UNIQUE, DEFAULT:
+	case CONSTRAINT, PRIMARY, NOT, UNIQUE, DEFAULT, CHECK, COLLATE, REFERENCES:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Parser) isTableConstraintKeyword() bool {
+	switch p.currentToken.Type {
+	case CONSTRAINT, PRIMARY, UNIQUE, CHECK, FOREIGN:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Parser) isCompoundOperator() bool {
+	switch p.currentToken.Type {
+	case UNION, INTERSECT, EXCEPT:
 		return true
 	default:
 		return false
 	}
 }
 
-func (p *Parser) isComparisonOperator() bool {
+func (p *Parser) isConflictResolution() bool {
 	switch p.currentToken.Type {
-	case EQUAL, NOT_EQUAL, NOT_EQUAL2, GREATER, LESS, GREATER_EQUAL, LESS_EQUAL, LIKE:
+	case ROLLBACK, ABORT, REPLACE, FAIL, IGNORE:
 		return true
 	default:
 		return false
 	}
 }
 
-func (p *Parser) Errors() []string {
+// FileSet returns the file set that positions in the parsed AST refer to.
+func (p *Parser) FileSet() *token.FileSet {
+	return p.fset
+}
+
+// pos returns the position of the current token.
+func (p *Parser) pos() token.Pos {
+	return p.file.Pos(p.currentToken.Offset)
+}
+
+// tokenEnd returns the position just past the current token.
+func (p *Parser) tokenEnd() token.Pos {
+	return p.file.Pos(p.currentToken.EndOffset)
+}
+
+// line returns the line number of a byte offset in the input.
+func (p *Parser) line(offset int) int {
+	return p.file.Line(p.file.Pos(offset))
+}
+
+// Comments returns every comment group read so far, in source order. It is
+// empty unless the lexer was created with WithComments or the parser with
+// WithCommentMap.
+func (p *Parser) Comments() []*CommentGroup {
+	return p.comments
+}
+
+// CommentMap returns the comments attached to the statements returned by
+// Next and to the nodes inside them.
+func (p *Parser) CommentMap() CommentMap {
+	return p.commentMap
+}
+
+// end returns the position just past the most recently consumed token.
+func (p *Parser) end() token.Pos {
+	return p.file.Pos(p.prevEnd)
+}
+
+// Errors returns every error the parser has reported so far.
+func (p *Parser) Errors() ErrorList {
 	return p.errors
 }
 
@@ -1,6 +1,10 @@
 package citrinelexer
 
 import (
+	"errors"
+	"go/token"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -76,174 +80,2299 @@ func TestParseCreateTable(t *testing.T) {
 	}
 }
 
-func TestParseInsert(t *testing.T) {
-	sql := "INSERT users"
+func TestParseCreateTableConstraints(t *testing.T) {
+	sql := `CREATE TEMP TABLE IF NOT EXISTS main.orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		code VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE,
+		price DECIMAL(10, 2) DEFAULT 0 CHECK (price >= 0),
+		status TEXT DEFAULT 'new' NOT NULL,
+		user_id INTEGER CONSTRAINT fk_user REFERENCES users(id) ON DELETE CASCADE ON UPDATE SET NULL,
+		CONSTRAINT uq_code UNIQUE (code, user_id),
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE NO ACTION,
+		CHECK (status IN ('new', 'paid'))
+	) WITHOUT ROWID`
 
 	stmt, err := Parse(sql)
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
 
-	insertStmt, ok := stmt.(*InsertStatement)
-	if !ok {
-		t.Fatalf("Expected InsertStatement, got %T", stmt)
+	createStmt := stmt.(*CreateTableStatement)
+
+	if !createStmt.Temporary || !createStmt.IfNotExists || !createStmt.WithoutRowID {
+		t.Fatal("Expected TEMP, IF NOT EXISTS and WITHOUT ROWID")
 	}
 
-	if insertStmt.Table.Name != "users" {
-		t.Fatalf("Expected table name 'users', got '%s'", insertStmt.Table.Name)
+	if createStmt.Schema == nil || createStmt.Schema.Name != "main" || createStmt.Table.Name != "orders" {
+		t.Fatal("Expected table main.orders")
 	}
-}
 
-func TestParseUpdate(t *testing.T) {
-	sql := "UPDATE users"
+	if len(createStmt.Columns) != 5 {
+		t.Fatalf("Expected 5 columns, got %d", len(createStmt.Columns))
+	}
 
-	stmt, err := Parse(sql)
+	if len(createStmt.Constraints) != 3 {
+		t.Fatalf("Expected 3 table constraints, got %d", len(createStmt.Constraints))
+	}
+
+	id := createStmt.Columns[0].Constraints[0].(*PrimaryKeyConstraint)
+	if !id.Autoincrement {
+		t.Fatal("Expected AUTOINCREMENT on id")
+	}
+
+	code := createStmt.Columns[1]
+	if code.Type != "VARCHAR" || len(code.TypeArgs) != 1 || code.TypeArgs[0] != "255" {
+		t.Fatalf("Expected VARCHAR(255), got %s%v", code.Type, code.TypeArgs)
+	}
+	if len(code.Constraints) != 3 {
+		t.Fatalf("Expected 3 constraints on code, got %d", len(code.Constraints))
+	}
+	if collate, ok := code.Constraints[2].(*CollateConstraint); !ok || collate.Collation != "NOCASE" {
+		t.Fatal("Expected COLLATE NOCASE on code")
+	}
+
+	price := createStmt.Columns[2]
+	if len(price.TypeArgs) != 2 {
+		t.Fatalf("Expected DECIMAL(10, 2), got %s%v", price.Type, price.TypeArgs)
+	}
+	if _, ok := price.Constraints[1].(*CheckConstraint); !ok {
+		t.Fatalf("Expected CHECK constraint on price, got %T", price.Constraints[1])
+	}
+
+	status := createStmt.Columns[3]
+	if len(status.Constraints) != 2 {
+		t.Fatalf("Expected DEFAULT and NOT NULL on status, got %d constraints", len(status.Constraints))
+	}
+
+	fk := createStmt.Columns[4].Constraints[0].(*ForeignKeyConstraint)
+	if fk.Name == nil || fk.Name.Name != "fk_user" || fk.Table.Name != "users" {
+		t.Fatal("Expected named REFERENCES users constraint")
+	}
+	if fk.OnDelete != "CASCADE" || fk.OnUpdate != "SET NULL" {
+		t.Fatalf("Expected ON DELETE CASCADE ON UPDATE SET NULL, got %s / %s", fk.OnDelete, fk.OnUpdate)
+	}
+
+	unique := createStmt.Constraints[0].(*UniqueConstraint)
+	if unique.Name.Name != "uq_code" || len(unique.Columns) != 2 {
+		t.Fatal("Expected table constraint uq_code on 2 columns")
+	}
+
+	tableFK := createStmt.Constraints[1].(*ForeignKeyConstraint)
+	if len(tableFK.Columns) != 1 || tableFK.OnDelete != "NO ACTION" {
+		t.Fatal("Expected FOREIGN KEY (user_id) with ON DELETE NO ACTION")
+	}
+}
+
+func TestParseCreateTableAsSelect(t *testing.T) {
+	stmt, err := Parse("CREATE TABLE archive AS SELECT * FROM orders WHERE paid = 1")
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
 
-	updateStmt, ok := stmt.(*UpdateStatement)
-	if !ok {
-		t.Fatalf("Expected UpdateStatement, got %T", stmt)
+	createStmt := stmt.(*CreateTableStatement)
+	if createStmt.AsSelect == nil {
+		t.Fatal("Expected AS SELECT query")
 	}
 
-	if updateStmt.Table.Name != "users" {
-		t.Fatalf("Expected table name 'users', got '%s'", updateStmt.Table.Name)
+	if len(createStmt.Columns) != 0 {
+		t.Fatalf("Expected no column definitions, got %d", len(createStmt.Columns))
 	}
 }
 
-func TestParseDelete(t *testing.T) {
-	sql := "DELETE FROM users WHERE id = 123"
+func TestParseTableConstraintColumns(t *testing.T) {
+	sql := `CREATE TABLE t (
+		a TEXT,
+		b INTEGER REFERENCES u(id) NOT DEFERRABLE NOT NULL,
+		PRIMARY KEY (a COLLATE NOCASE DESC, b),
+		UNIQUE (b DESC),
+		FOREIGN KEY (b) REFERENCES u (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED
+	)`
 
 	stmt, err := Parse(sql)
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
 
-	deleteStmt, ok := stmt.(*DeleteStatement)
-	if !ok {
-		t.Fatalf("Expected DeleteStatement, got %T", stmt)
+	createStmt := stmt.(*CreateTableStatement)
+
+	columnFK := createStmt.Columns[1].Constraints[0].(*ForeignKeyConstraint)
+	if columnFK.Deferrable != "NOT DEFERRABLE" || columnFK.Initially != "" {
+		t.Fatalf("Expected NOT DEFERRABLE, got %q %q", columnFK.Deferrable, columnFK.Initially)
+	}
+	if _, ok := createStmt.Columns[1].Constraints[1].(*NotNullConstraint); !ok {
+		t.Fatalf("Expected NOT NULL after NOT DEFERRABLE, got %T", createStmt.Columns[1].Constraints[1])
 	}
 
-	if deleteStmt.From.Name != "users" {
-		t.Fatalf("Expected table name 'users', got '%s'", deleteStmt.From.Name)
+	pk := createStmt.Constraints[0].(*PrimaryKeyConstraint)
+	if len(pk.Columns) != 2 || pk.Columns[0].Direction != "DESC" || pk.Columns[1].Direction != "ASC" {
+		t.Fatalf("Expected PRIMARY KEY (a DESC, b), got %v", pk.Columns)
+	}
+	if _, ok := pk.Columns[0].Expression.(*CollateExpression); !ok {
+		t.Fatalf("Expected COLLATE on first key column, got %T", pk.Columns[0].Expression)
 	}
 
-	if deleteStmt.Where == nil {
-		t.Fatal("Expected WHERE clause")
+	unique := createStmt.Constraints[1].(*UniqueConstraint)
+	if len(unique.Columns) != 1 || unique.Columns[0].Direction != "DESC" {
+		t.Fatalf("Expected UNIQUE (b DESC), got %v", unique.Columns)
+	}
+
+	fk := createStmt.Constraints[2].(*ForeignKeyConstraint)
+	if fk.OnDelete != "CASCADE" || fk.Deferrable != "DEFERRABLE" || fk.Initially != "DEFERRED" {
+		t.Fatalf("Expected ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED, got %q %q %q", fk.OnDelete, fk.Deferrable, fk.Initially)
 	}
 }
 
-func TestParseParameters(t *testing.T) {
+func TestParseCreateTableErrors(t *testing.T) {
 	tests := []struct {
 		name string
 		sql  string
 	}{
 		{
-			name: "positional parameter",
-			sql:  "SELECT * FROM users WHERE id = ?",
+			name: "column after table constraint",
+			sql:  "CREATE TABLE t (a INTEGER, PRIMARY KEY (a), b TEXT)",
 		},
 		{
-			name: "named parameter colon",
-			sql:  "SELECT * FROM users WHERE name = :name",
+			name: "bad type argument",
+			sql:  "CREATE TABLE t (a VARCHAR(x))",
 		},
 		{
-			name: "named parameter dollar",
-			sql:  "SELECT * FROM users WHERE age = $age",
+			name: "bad foreign key action",
+			sql:  "CREATE TABLE t (a INTEGER REFERENCES u(id) ON DELETE NOTHING)",
+		},
+		{
+			name: "if without not exists",
+			sql:  "CREATE TABLE IF t (a INTEGER)",
+		},
+		{
+			name: "no columns",
+			sql:  "CREATE TABLE t ()",
+		},
+		{
+			name: "only a table constraint",
+			sql:  "CREATE TABLE t (PRIMARY KEY (a))",
+		},
+		{
+			name: "trailing comma",
+			sql:  "CREATE TABLE t (a INTEGER,)",
+		},
+		{
+			name: "bad INITIALLY",
+			sql:  "CREATE TABLE t (a INTEGER REFERENCES u(id) DEFERRABLE INITIALLY LATER)",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sql)
+			if err == nil {
+				t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseDropStatement(t *testing.T) {
+	tests := []struct {
+		sql      string
+		kind     string
+		ifExists bool
+		name     string
+	}{
+		{"DROP TABLE users", "TABLE", false, "users"},
+		{"DROP INDEX IF EXISTS main.idx_users_email", "INDEX", true, "idx_users_email"},
+		{"DROP VIEW active_users", "VIEW", false, "active_users"},
+		{"DROP TRIGGER IF EXISTS audit", "TRIGGER", true, "audit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
 			stmt, err := Parse(tt.sql)
 			if err != nil {
 				t.Fatalf("Parse failed: %v", err)
 			}
 
-			selectStmt, ok := stmt.(*SelectStatement)
+			dropStmt, ok := stmt.(*DropStatement)
 			if !ok {
-				t.Fatalf("Expected SelectStatement, got %T", stmt)
+				t.Fatalf("Expected DropStatement, got %T", stmt)
 			}
 
-			if selectStmt.Where == nil {
-				t.Fatal("Expected WHERE clause with parameter")
+			if dropStmt.Kind != tt.kind || dropStmt.IfExists != tt.ifExists || dropStmt.Name.Name != tt.name {
+				t.Fatalf("Expected DROP %s %s (if exists %t), got DROP %s %s (if exists %t)",
+					tt.kind, tt.name, tt.ifExists, dropStmt.Kind, dropStmt.Name.Name, dropStmt.IfExists)
 			}
 		})
 	}
 }
 
-func TestParseFunctionCall(t *testing.T) {
-	sql := "SELECT name FROM users"
+func TestParseAlterTable(t *testing.T) {
+	tests := []struct {
+		sql    string
+		action string
+		check  func(*AlterTableStatement) bool
+	}{
+		{
+			sql:    "ALTER TABLE users RENAME TO customers",
+			action: "RENAME TO",
+			check:  func(a *AlterTableStatement) bool { return a.NewName.Name == "customers" },
+		},
+		{
+			sql:    "ALTER TABLE main.users RENAME COLUMN name TO full_name",
+			action: "RENAME COLUMN",
+			check: func(a *AlterTableStatement) bool {
+				return a.Schema.Name == "main" && a.Column.Name == "name" && a.NewName.Name == "full_name"
+			},
+		},
+		{
+			sql:    "ALTER TABLE users ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT ''",
+			action: "ADD COLUMN",
+			check: func(a *AlterTableStatement) bool {
+				return a.ColumnDef.Name.Name == "email" && len(a.ColumnDef.Constraints) == 2
+			},
+		},
+		{
+			sql:    "ALTER TABLE users DROP legacy",
+			action: "DROP COLUMN",
+			check:  func(a *AlterTableStatement) bool { return a.Column.Name == "legacy" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			alterStmt, ok := stmt.(*AlterTableStatement)
+			if !ok {
+				t.Fatalf("Expected AlterTableStatement, got %T", stmt)
+			}
+
+			if alterStmt.Action != tt.action {
+				t.Fatalf("Expected action '%s', got '%s'", tt.action, alterStmt.Action)
+			}
+
+			if !tt.check(alterStmt) {
+				t.Fatalf("Unexpected AlterTableStatement for %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseAlterTableErrors(t *testing.T) {
+	for _, sql := range []string{
+		"ALTER TABLE users RENAME TO",
+		"ALTER TABLE users RENAME a TO",
+		"ALTER TABLE users ADD COLUMN",
+		"ALTER TABLE users DROP COLUMN",
+	} {
+		stmt, err := NewParser(NewLexer(sql)).parseAlterTableStatement()
+		if err == nil {
+			t.Fatalf("Expected error for invalid SQL: %s", sql)
+		}
+		if stmt != nil {
+			t.Fatalf("Expected no statement for invalid SQL %s, got %+v", sql, stmt)
+		}
+	}
+}
+
+func TestParseIndexDirectionCase(t *testing.T) {
+	stmt, err := Parse("CREATE INDEX i ON t(a desc, b Asc)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	index := stmt.(*CreateIndexStatement)
+	if len(index.Columns) != 2 || index.Columns[0].Direction != "DESC" || index.Columns[1].Direction != "ASC" {
+		t.Fatalf("Expected columns a DESC, b ASC, got %v", index.Columns)
+	}
+}
+
+func TestParseCreateIndexAndView(t *testing.T) {
+	stmt, err := Parse("CREATE UNIQUE INDEX IF NOT EXISTS idx_email ON users (lower(email) COLLATE NOCASE, created_at DESC) WHERE deleted = 0")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	index, ok := stmt.(*CreateIndexStatement)
+	if !ok {
+		t.Fatalf("Expected CreateIndexStatement, got %T", stmt)
+	}
+
+	if !index.Unique || !index.IfNotExists || index.Table.Name != "users" {
+		t.Fatal("Expected UNIQUE INDEX IF NOT EXISTS on users")
+	}
+
+	if len(index.Columns) != 2 || index.Columns[1].Direction != "DESC" {
+		t.Fatal("Expected 2 indexed columns, the second DESC")
+	}
+
+	if _, ok := index.Columns[0].Expression.(*CollateExpression); !ok {
+		t.Fatalf("Expected COLLATE on first indexed column, got %T", index.Columns[0].Expression)
+	}
+
+	if index.Where == nil {
+		t.Fatal("Expected partial index WHERE clause")
+	}
+
+	stmt, err = Parse("CREATE TEMP VIEW IF NOT EXISTS active (id, name) AS SELECT id, name FROM users WHERE active = 1")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	view, ok := stmt.(*CreateViewStatement)
+	if !ok {
+		t.Fatalf("Expected CreateViewStatement, got %T", stmt)
+	}
+
+	if !view.Temporary || !view.IfNotExists || len(view.Columns) != 2 || view.Query == nil {
+		t.Fatal("Expected TEMP VIEW IF NOT EXISTS with 2 columns and a query")
+	}
+}
+
+func TestParseCreateTrigger(t *testing.T) {
+	sql := `CREATE TRIGGER IF NOT EXISTS audit_email AFTER UPDATE OF email ON users
+		FOR EACH ROW WHEN old.email <> new.email
+		BEGIN
+			INSERT INTO audit (user_id, old_email) VALUES (old.id, old.email);
+			UPDATE users SET updated = 1 WHERE id = new.id;
+		END`
 
 	stmt, err := Parse(sql)
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
 
-	selectStmt, ok := stmt.(*SelectStatement)
+	trigger, ok := stmt.(*CreateTriggerStatement)
 	if !ok {
-		t.Fatalf("Expected SelectStatement, got %T", stmt)
+		t.Fatalf("Expected CreateTriggerStatement, got %T", stmt)
 	}
 
-	if len(selectStmt.Fields) != 1 {
-		t.Fatalf("Expected 1 field, got %d", len(selectStmt.Fields))
+	if trigger.Time != "AFTER" || trigger.Event != "UPDATE" || len(trigger.UpdateOf) != 1 {
+		t.Fatalf("Expected AFTER UPDATE OF email, got %s %s", trigger.Time, trigger.Event)
+	}
+
+	if !trigger.ForEachRow || trigger.When == nil {
+		t.Fatal("Expected FOR EACH ROW WHEN clause")
+	}
+
+	if len(trigger.Body) != 2 {
+		t.Fatalf("Expected 2 body statements, got %d", len(trigger.Body))
+	}
+
+	if _, ok := trigger.Body[0].(*InsertStatement); !ok {
+		t.Fatalf("Expected INSERT in trigger body, got %T", trigger.Body[0])
 	}
 }
 
-func TestParseExpressions(t *testing.T) {
+func TestParseDDLErrors(t *testing.T) {
 	tests := []struct {
 		name string
 		sql  string
+	}{
+		{name: "drop without kind", sql: "DROP users"},
+		{name: "alter without action", sql: "ALTER TABLE users"},
+		{name: "unique table", sql: "CREATE UNIQUE TABLE t (a INTEGER)"},
+		{name: "index without on", sql: "CREATE INDEX idx users (a)"},
+		{name: "trigger without semicolon", sql: "CREATE TRIGGER t AFTER INSERT ON users BEGIN DELETE FROM x END"},
+		{name: "empty trigger body", sql: "CREATE TRIGGER t AFTER INSERT ON users BEGIN END"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sql)
+			if err == nil {
+				t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseDatabaseCommands(t *testing.T) {
+	tests := []struct {
+		sql   string
+		check func(Statement) bool
 	}{
 		{
-			name: "string literal",
-			sql:  "SELECT 'hello' FROM users",
+			sql: "PRAGMA table_info(users)",
+			check: func(s Statement) bool {
+				p, ok := s.(*PragmaStatement)
+				return ok && p.Name.Name == "table_info" && p.Value.String() == "users"
+			},
 		},
 		{
-			name: "number literal",
-			sql:  "SELECT 42 FROM users",
+			sql: "PRAGMA main.foreign_keys = ON",
+			check: func(s Statement) bool {
+				p, ok := s.(*PragmaStatement)
+				return ok && p.Schema.Name == "main" && p.Value.String() == "ON"
+			},
 		},
 		{
-			name: "boolean literal",
-			sql:  "SELECT TRUE FROM users",
+			sql: "PRAGMA cache_size = -2000",
+			check: func(s Statement) bool {
+				p, ok := s.(*PragmaStatement)
+				return ok && p.Value.String() == "-2000"
+			},
 		},
 		{
-			name: "binary expression",
-			sql:  "SELECT * FROM users WHERE age > 18",
+			sql: "VACUUM",
+			check: func(s Statement) bool {
+				v, ok := s.(*VacuumStatement)
+				return ok && v.Schema == nil && v.Into == nil
+			},
+		},
+		{
+			sql: "VACUUM main INTO 'backup.db'",
+			check: func(s Statement) bool {
+				v, ok := s.(*VacuumStatement)
+				return ok && v.Schema.Name == "main" && v.Into != nil
+			},
+		},
+		{
+			sql: "EXPLAIN QUERY PLAN SELECT * FROM users",
+			check: func(s Statement) bool {
+				e, ok := s.(*ExplainStatement)
+				if !ok || !e.QueryPlan {
+					return false
+				}
+				_, ok = e.Statement.(*SelectStatement)
+				return ok
+			},
+		},
+		{
+			sql: "EXPLAIN DELETE FROM users",
+			check: func(s Statement) bool {
+				e, ok := s.(*ExplainStatement)
+				if !ok || e.QueryPlan {
+					return false
+				}
+				_, ok = e.Statement.(*DeleteStatement)
+				return ok
+			},
+		},
+		{
+			sql: "ATTACH DATABASE 'backup.db' AS backup",
+			check: func(s Statement) bool {
+				a, ok := s.(*AttachStatement)
+				return ok && a.Database.String() == "'backup.db'" && a.Schema.Name == "backup"
+			},
+		},
+		{
+			sql: "DETACH backup",
+			check: func(s Statement) bool {
+				d, ok := s.(*DetachStatement)
+				return ok && d.Schema.Name == "backup"
+			},
+		},
+		{
+			sql: "REINDEX main.idx_users",
+			check: func(s Statement) bool {
+				r, ok := s.(*ReindexStatement)
+				return ok && r.Schema.Name == "main" && r.Name.Name == "idx_users"
+			},
+		},
+		{
+			sql: "ANALYZE",
+			check: func(s Statement) bool {
+				a, ok := s.(*AnalyzeStatement)
+				return ok && a.Name == nil
+			},
 		},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+		t.Run(tt.sql, func(t *testing.T) {
 			stmt, err := Parse(tt.sql)
 			if err != nil {
 				t.Fatalf("Parse failed: %v", err)
 			}
 
-			_, ok := stmt.(*SelectStatement)
-			if !ok {
-				t.Fatalf("Expected SelectStatement, got %T", stmt)
+			if !tt.check(stmt) {
+				t.Fatalf("Unexpected %T for %s", stmt, tt.sql)
 			}
 		})
 	}
+
+	for _, sql := range []string{
+		"ATTACH 'x.db'",
+		"DETACH DATABASE",
+		"EXPLAIN EXPLAIN SELECT 1",
+		"PRAGMA",
+	} {
+		if _, err := Parse(sql); err == nil {
+			t.Fatalf("Expected error for invalid SQL: %s", sql)
+		}
+	}
 }
 
-func TestParseErrors(t *testing.T) {
+func TestParseTransactionStatements(t *testing.T) {
 	tests := []struct {
-		name string
-		sql  string
+		sql   string
+		check func(Statement) bool
 	}{
 		{
-			name: "invalid token",
-			sql:  "INVALID STATEMENT",
+			sql: "BEGIN",
+			check: func(s Statement) bool {
+				b, ok := s.(*BeginStatement)
+				return ok && b.Mode == ""
+			},
 		},
 		{
-			name: "incomplete select",
-			sql:  "SELECT",
+			sql: "BEGIN IMMEDIATE TRANSACTION",
+			check: func(s Statement) bool {
+				b, ok := s.(*BeginStatement)
+				return ok && b.Mode == "IMMEDIATE"
+			},
 		},
 		{
-			name: "missing table name",
-			sql:  "SELECT * FROM",
+			sql: "COMMIT TRANSACTION",
+			check: func(s Statement) bool {
+				_, ok := s.(*CommitStatement)
+				return ok
+			},
+		},
+		{
+			sql: "END",
+			check: func(s Statement) bool {
+				_, ok := s.(*CommitStatement)
+				return ok
+			},
+		},
+		{
+			sql: "ROLLBACK",
+			check: func(s Statement) bool {
+				r, ok := s.(*RollbackStatement)
+				return ok && r.Savepoint == nil
+			},
+		},
+		{
+			sql: "ROLLBACK TRANSACTION TO SAVEPOINT sp1",
+			check: func(s Statement) bool {
+				r, ok := s.(*RollbackStatement)
+				return ok && r.Savepoint.Name == "sp1"
+			},
+		},
+		{
+			sql: "SAVEPOINT sp1",
+			check: func(s Statement) bool {
+				sp, ok := s.(*SavepointStatement)
+				return ok && sp.Name.Name == "sp1"
+			},
+		},
+		{
+			sql: "RELEASE SAVEPOINT sp1",
+			check: func(s Statement) bool {
+				r, ok := s.(*ReleaseStatement)
+				return ok && r.Name.Name == "sp1"
+			},
+		},
+		{
+			sql: "RELEASE sp2",
+			check: func(s Statement) bool {
+				r, ok := s.(*ReleaseStatement)
+				return ok && r.Name.Name == "sp2"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if !tt.check(stmt) {
+				t.Fatalf("Unexpected %T for %s", stmt, tt.sql)
+			}
+		})
+	}
+
+	for _, sql := range []string{"SAVEPOINT", "ROLLBACK TO", "RELEASE SAVEPOINT"} {
+		if _, err := Parse(sql); err == nil {
+			t.Fatalf("Expected error for invalid SQL: %s", sql)
+		}
+	}
+}
+
+func TestParseInsert(t *testing.T) {
+	sql := "INSERT INTO main.users VALUES (1)"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	insertStmt, ok := stmt.(*InsertStatement)
+	if !ok {
+		t.Fatalf("Expected InsertStatement, got %T", stmt)
+	}
+
+	if insertStmt.Table.Name != "users" {
+		t.Fatalf("Expected table name 'users', got '%s'", insertStmt.Table.Name)
+	}
+
+	if insertStmt.Schema == nil || insertStmt.Schema.Name != "main" {
+		t.Fatalf("Expected schema 'main', got %v", insertStmt.Schema)
+	}
+}
+
+func TestParseInsertValues(t *testing.T) {
+	sql := "INSERT INTO users (id, name) VALUES (1, 'John'), (2, 'Jane')"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	insertStmt, ok := stmt.(*InsertStatement)
+	if !ok {
+		t.Fatalf("Expected InsertStatement, got %T", stmt)
+	}
+
+	if insertStmt.Table.Name != "users" {
+		t.Fatalf("Expected table name 'users', got '%s'", insertStmt.Table.Name)
+	}
+
+	if len(insertStmt.Columns) != 2 {
+		t.Fatalf("Expected 2 columns, got %d", len(insertStmt.Columns))
+	}
+
+	if len(insertStmt.Values) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(insertStmt.Values))
+	}
+
+	if len(insertStmt.Values[1]) != 2 {
+		t.Fatalf("Expected 2 values in second row, got %d", len(insertStmt.Values[1]))
+	}
+}
+
+func TestParseInsertForms(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		check   func(*InsertStatement) bool
+		wantErr bool
+	}{
+		{
+			name:  "default values",
+			sql:   "INSERT INTO users DEFAULT VALUES",
+			check: func(s *InsertStatement) bool { return s.DefaultValues },
+		},
+		{
+			name: "insert select",
+			sql:  "INSERT INTO archive SELECT * FROM users",
+			check: func(s *InsertStatement) bool {
+				sel, ok := s.Select.(*SelectStatement)
+				return ok && len(sel.Fields) == 1
+			},
+		},
+		{
+			name:  "values without column list",
+			sql:   "INSERT INTO users VALUES (1, 'John', 42)",
+			check: func(s *InsertStatement) bool { return len(s.Columns) == 0 && len(s.Values[0]) == 3 },
+		},
+		{
+			name:    "column count mismatch",
+			sql:     "INSERT INTO users (id, name) VALUES (1)",
+			wantErr: true,
+		},
+		{
+			name:  "insert or replace",
+			sql:   "INSERT OR REPLACE INTO users VALUES (1)",
+			check: func(s *InsertStatement) bool { return s.OnConflict == "REPLACE" },
+		},
+		{
+			name:  "replace into",
+			sql:   "REPLACE INTO users (id) VALUES (1)",
+			check: func(s *InsertStatement) bool { return s.OnConflict == "REPLACE" && len(s.Columns) == 1 },
+		},
+		{
+			name:  "insert or ignore",
+			sql:   "INSERT OR IGNORE INTO users DEFAULT VALUES",
+			check: func(s *InsertStatement) bool { return s.OnConflict == "IGNORE" && s.DefaultValues },
+		},
+		{
+			name:    "missing row source",
+			sql:     "INSERT INTO users",
+			wantErr: true,
+		},
+		{
+			name:    "missing row source without INTO",
+			sql:     "INSERT users",
+			wantErr: true,
+		},
+		{
+			name:    "bad conflict clause",
+			sql:     "INSERT OR DELETE INTO users VALUES (1)",
+			wantErr: true,
+		},
+		{
+			name:    "row length mismatch",
+			sql:     "INSERT INTO t VALUES (1), (2, 3)",
+			wantErr: true,
+		},
+		{
+			name:    "default without values",
+			sql:     "INSERT INTO users DEFAULT",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			insertStmt, ok := stmt.(*InsertStatement)
+			if !ok {
+				t.Fatalf("Expected InsertStatement, got %T", stmt)
+			}
+
+			if !tt.check(insertStmt) {
+				t.Fatalf("Unexpected InsertStatement for %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseUpdate(t *testing.T) {
+	sql := "UPDATE main.users SET active = 1"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	updateStmt, ok := stmt.(*UpdateStatement)
+	if !ok {
+		t.Fatalf("Expected UpdateStatement, got %T", stmt)
+	}
+
+	if updateStmt.Table.Name != "users" {
+		t.Fatalf("Expected table name 'users', got '%s'", updateStmt.Table.Name)
+	}
+
+	if updateStmt.Schema == nil || updateStmt.Schema.Name != "main" {
+		t.Fatalf("Expected schema 'main', got %v", updateStmt.Schema)
+	}
+}
+
+func TestParseUpdateSet(t *testing.T) {
+	sql := "UPDATE OR REPLACE users SET name = 'Jane', age = 30, (x, y) = (1, 2) WHERE id = 1"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	updateStmt, ok := stmt.(*UpdateStatement)
+	if !ok {
+		t.Fatalf("Expected UpdateStatement, got %T", stmt)
+	}
+
+	if updateStmt.OnConflict != "REPLACE" {
+		t.Fatalf("Expected conflict clause 'REPLACE', got '%s'", updateStmt.OnConflict)
+	}
+
+	if len(updateStmt.Set) != 3 {
+		t.Fatalf("Expected 3 assignments, got %d", len(updateStmt.Set))
+	}
+
+	if updateStmt.Set[0].Column.Name != "name" {
+		t.Fatalf("Expected first column 'name', got '%s'", updateStmt.Set[0].Column.Name)
+	}
+
+	if len(updateStmt.Set[2].Columns) != 2 || len(updateStmt.Set[2].Values) != 2 {
+		t.Fatal("Expected row-value assignment with 2 columns and 2 values")
+	}
+
+	if updateStmt.Where == nil {
+		t.Fatal("Expected WHERE clause")
+	}
+}
+
+func TestParseUpdateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "missing set",
+			sql:  "UPDATE users",
+		},
+		{
+			name: "missing table after schema",
+			sql:  "UPDATE main. SET a = 1",
+		},
+		{
+			name: "missing equals",
+			sql:  "UPDATE users SET name 'Jane'",
+		},
+		{
+			name: "bad conflict clause",
+			sql:  "UPDATE OR DELETE users SET a = 1",
+		},
+		{
+			name: "row value count mismatch",
+			sql:  "UPDATE users SET (a, b) = (1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sql)
+			if err == nil {
+				t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseDelete(t *testing.T) {
+	sql := "DELETE FROM users WHERE id = 123"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	deleteStmt, ok := stmt.(*DeleteStatement)
+	if !ok {
+		t.Fatalf("Expected DeleteStatement, got %T", stmt)
+	}
+
+	if deleteStmt.From.Name != "users" {
+		t.Fatalf("Expected table name 'users', got '%s'", deleteStmt.From.Name)
+	}
+
+	if deleteStmt.Where == nil {
+		t.Fatal("Expected WHERE clause")
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "positional parameter",
+			sql:  "SELECT * FROM users WHERE id = ?",
+		},
+		{
+			name: "named parameter colon",
+			sql:  "SELECT * FROM users WHERE name = :name",
+		},
+		{
+			name: "named parameter dollar",
+			sql:  "SELECT * FROM users WHERE age = $age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			selectStmt, ok := stmt.(*SelectStatement)
+			if !ok {
+				t.Fatalf("Expected SelectStatement, got %T", stmt)
+			}
+
+			if selectStmt.Where == nil {
+				t.Fatal("Expected WHERE clause with parameter")
+			}
+		})
+	}
+}
+
+func TestParameterIndex(t *testing.T) {
+	stmt, err := Parse("SELECT ?, ?5, ?, :a, :a, @b, $c")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+	expected := []struct {
+		name  string
+		index int
+	}{
+		{"", 1},
+		{"?5", 5},
+		{"", 6},
+		{":a", 7},
+		{":a", 7},
+		{"@b", 8},
+		{"$c", 9},
+	}
+
+	if len(selectStmt.Fields) != len(expected) {
+		t.Fatalf("Expected %d columns, got %d", len(expected), len(selectStmt.Fields))
+	}
+
+	for i, want := range expected {
+		param, ok := selectStmt.Fields[i].(*Parameter)
+		if !ok {
+			t.Fatalf("column %d: expected Parameter, got %T", i, selectStmt.Fields[i])
+		}
+		if param.Name != want.name || param.Index != want.index {
+			t.Errorf("column %d: expected %q index %d, got %q index %d",
+				i, want.name, want.index, param.Name, param.Index)
+		}
+	}
+}
+
+func TestParseBlobLiteral(t *testing.T) {
+	stmt, err := Parse("INSERT INTO files (data) VALUES (X'CAFE')")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	insertStmt := stmt.(*InsertStatement)
+	blob, ok := insertStmt.Values[0][0].(*BlobLiteral)
+	if !ok {
+		t.Fatalf("Expected BlobLiteral, got %T", insertStmt.Values[0][0])
+	}
+
+	if blob.Value != "CAFE" {
+		t.Errorf("Expected blob value 'CAFE', got '%s'", blob.Value)
+	}
+
+	if blob.String() != "X'CAFE'" {
+		t.Errorf("Expected X'CAFE', got %s", blob.String())
+	}
+}
+
+func TestParseFunctionCall(t *testing.T) {
+	sql := "SELECT name FROM users"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt, ok := stmt.(*SelectStatement)
+	if !ok {
+		t.Fatalf("Expected SelectStatement, got %T", stmt)
+	}
+
+	if len(selectStmt.Fields) != 1 {
+		t.Fatalf("Expected 1 field, got %d", len(selectStmt.Fields))
+	}
+}
+
+func TestParseFunctionCallForms(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "count star",
+			sql:  "SELECT COUNT(*) FROM users",
+			want: "COUNT(*)",
+		},
+		{
+			name: "arbitrary function name",
+			sql:  "SELECT lower(name) FROM users",
+			want: "lower(name)",
+		},
+		{
+			name: "keyword function name",
+			sql:  "SELECT REPLACE(name, 'a', 'b') FROM users",
+			want: "REPLACE(name, 'a', 'b')",
+		},
+		{
+			name: "distinct argument",
+			sql:  "SELECT COUNT(DISTINCT city) FROM users",
+			want: "COUNT(DISTINCT city)",
+		},
+		{
+			name: "qualified column argument",
+			sql:  "SELECT max(u.age) FROM users u",
+			want: "max(u.age)",
+		},
+		{
+			name: "nested calls",
+			sql:  "SELECT coalesce(nullif(a, ''), glob('*x', b)) FROM t",
+			want: "coalesce(nullif(a, ''), glob('*x', b))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			call, ok := stmt.(*SelectStatement).Fields[0].(*FunctionCall)
+			if !ok {
+				t.Fatalf("Expected FunctionCall, got %T", stmt.(*SelectStatement).Fields[0])
+			}
+
+			if call.String() != tt.want {
+				t.Fatalf("Expected '%s', got '%s'", tt.want, call.String())
+			}
+		})
+	}
+}
+
+func TestParseKeywordCalls(t *testing.T) {
+	stmt, err := Parse("SELECT DISTINCT(name) FROM t")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+	if !selectStmt.Distinct {
+		t.Error("Expected SELECT DISTINCT")
+	}
+	if _, ok := selectStmt.Fields[0].(*ParenExpression); !ok {
+		t.Fatalf("Expected ParenExpression, got %T", selectStmt.Fields[0])
+	}
+
+	for _, sql := range []string{
+		"SELECT a, DISTINCT(name) FROM t",
+		"SELECT WHERE(1)",
+		"SELECT FROM(1)",
+		"SELECT -NOT(1)",
+	} {
+		if _, err := Parse(sql); err == nil {
+			t.Errorf("Expected error for %q", sql)
+		}
+	}
+}
+
+func TestParseAggregateOrderBy(t *testing.T) {
+	stmt, err := Parse("SELECT group_concat(name, ',' ORDER BY name DESC) FROM users")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	call := stmt.(*SelectStatement).Fields[0].(*FunctionCall)
+	if len(call.Args) != 2 {
+		t.Fatalf("Expected 2 arguments, got %d", len(call.Args))
+	}
+
+	if len(call.OrderBy) != 1 || call.OrderBy[0].Direction != "DESC" {
+		t.Fatal("Expected ORDER BY name DESC inside the aggregate")
+	}
+}
+
+func TestParseQualifiedIdentifiers(t *testing.T) {
+	stmt, err := Parse("SELECT u.name, main.users.age, u.* FROM users u WHERE u.id > 100 ORDER BY u.name LIMIT 50")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+	if len(selectStmt.Fields) != 3 {
+		t.Fatalf("Expected 3 fields, got %d", len(selectStmt.Fields))
+	}
+
+	want := []string{"u.name", "main.users.age", "u.*"}
+	for i, field := range selectStmt.Fields {
+		ident, ok := field.(*QualifiedIdentifier)
+		if !ok {
+			t.Fatalf("Fields[%d]: expected QualifiedIdentifier, got %T", i, field)
+		}
+		if ident.String() != want[i] {
+			t.Fatalf("Fields[%d]: expected '%s', got '%s'", i, want[i], ident.String())
+		}
+	}
+
+	if selectStmt.Fields[1].(*QualifiedIdentifier).Schema.Name != "main" {
+		t.Fatal("Expected schema 'main'")
+	}
+
+	if selectStmt.From == nil || selectStmt.Where == nil || selectStmt.Limit == nil {
+		t.Fatal("Expected FROM, WHERE and LIMIT after qualified fields")
+	}
+}
+
+func TestParseExpressions(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "string literal",
+			sql:  "SELECT 'hello' FROM users",
+		},
+		{
+			name: "number literal",
+			sql:  "SELECT 42 FROM users",
+		},
+		{
+			name: "boolean literal",
+			sql:  "SELECT TRUE FROM users",
+		},
+		{
+			name: "binary expression",
+			sql:  "SELECT * FROM users WHERE age > 18",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			_, ok := stmt.(*SelectStatement)
+			if !ok {
+				t.Fatalf("Expected SelectStatement, got %T", stmt)
+			}
+		})
+	}
+}
+
+func TestParseOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		operator string
+		left     string
+		right    string
+	}{
+		{
+			name:     "AND binds tighter than OR",
+			sql:      "SELECT * FROM t WHERE a = 1 AND b = 2 OR c = 3",
+			operator: "OR",
+			left:     "a = 1 AND b = 2",
+			right:    "c = 3",
+		},
+		{
+			name:     "arithmetic binds tighter than comparison",
+			sql:      "SELECT * FROM t WHERE price * qty > 100",
+			operator: ">",
+			left:     "price * qty",
+			right:    "100",
+		},
+		{
+			name:     "multiplication binds tighter than addition",
+			sql:      "SELECT * FROM t WHERE a + b * c = 7",
+			operator: "=",
+			left:     "a + b * c",
+			right:    "7",
+		},
+		{
+			name:     "concatenation is left associative",
+			sql:      "SELECT * FROM t WHERE first || ' ' || last = 'a b'",
+			operator: "=",
+			left:     "first || ' ' || last",
+			right:    "'a b'",
+		},
+		{
+			name:     "keyword operators are upper-cased",
+			sql:      "SELECT * FROM t WHERE a = 1 and b NOT LIKE 'x%'",
+			operator: "AND",
+			left:     "a = 1",
+			right:    "b NOT LIKE 'x%'",
+		},
+		{
+			name:     "is not null",
+			sql:      "SELECT * FROM t WHERE a IS NOT NULL",
+			operator: "IS NOT",
+			left:     "a",
+			right:    "NULL",
+		},
+		{
+			name:     "between inside AND",
+			sql:      "SELECT * FROM t WHERE x BETWEEN 1 AND 10 AND y = 2",
+			operator: "AND",
+			left:     "x BETWEEN 1 AND 10",
+			right:    "y = 2",
+		},
+		{
+			name:     "bitwise binds looser than addition",
+			sql:      "SELECT * FROM t WHERE a << 1 + b",
+			operator: "<<",
+			left:     "a",
+			right:    "1 + b",
+		},
+		{
+			name:     "bitwise binds tighter than comparison",
+			sql:      "SELECT * FROM t WHERE flags & 4 | 1 >> 2 < 10",
+			operator: "<",
+			left:     "flags & 4 | 1 >> 2",
+			right:    "10",
+		},
+		{
+			name:     "unary bitwise not",
+			sql:      "SELECT * FROM t WHERE ~a = -1",
+			operator: "=",
+			left:     "~a",
+			right:    "-1",
+		},
+		{
+			name:     "like with escape",
+			sql:      "SELECT * FROM t WHERE name NOT LIKE 'a!%' ESCAPE '!' AND b = 1",
+			operator: "AND",
+			left:     "name NOT LIKE 'a!%' ESCAPE '!'",
+			right:    "b = 1",
+		},
+		{
+			name:     "parentheses override precedence",
+			sql:      "SELECT * FROM t WHERE (a + b) * c = 1",
+			operator: "=",
+			left:     "(a + b) * c",
+			right:    "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			where, ok := stmt.(*SelectStatement).Where.(*BinaryExpression)
+			if !ok {
+				t.Fatalf("Expected BinaryExpression, got %T", stmt.(*SelectStatement).Where)
+			}
+
+			if where.Operator != tt.operator {
+				t.Fatalf("Expected operator '%s', got '%s'", tt.operator, where.Operator)
+			}
+
+			if where.Left.String() != tt.left {
+				t.Fatalf("Expected left '%s', got '%s'", tt.left, where.Left.String())
+			}
+
+			if where.Right.String() != tt.right {
+				t.Fatalf("Expected right '%s', got '%s'", tt.right, where.Right.String())
+			}
+		})
+	}
+}
+
+func TestParseUnaryExpressions(t *testing.T) {
+	stmt, err := Parse("SELECT -x FROM t WHERE NOT a = 1 AND b = 2")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+
+	neg, ok := selectStmt.Fields[0].(*UnaryExpression)
+	if !ok || neg.Operator != "-" {
+		t.Fatalf("Expected unary minus, got %T", selectStmt.Fields[0])
+	}
+
+	and, ok := selectStmt.Where.(*BinaryExpression)
+	if !ok || and.Operator != "AND" {
+		t.Fatalf("Expected AND at the top of WHERE, got %s", selectStmt.Where)
+	}
+
+	not, ok := and.Left.(*UnaryExpression)
+	if !ok || not.Operator != "NOT" {
+		t.Fatalf("Expected NOT on the left of AND, got %T", and.Left)
+	}
+
+	if _, ok := not.Operand.(*BinaryExpression); !ok {
+		t.Fatalf("Expected NOT to apply to the comparison, got %T", not.Operand)
+	}
+}
+
+func TestParseJoins(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "inner join with on",
+			sql:  "SELECT * FROM users JOIN orders ON user_id = id",
+			want: "users INNER JOIN orders",
+		},
+		{
+			name: "left outer join with using",
+			sql:  "SELECT * FROM users u LEFT OUTER JOIN orders o USING (id)",
+			want: "users AS u LEFT JOIN orders AS o",
+		},
+		{
+			name: "comma join",
+			sql:  "SELECT * FROM a, b",
+			want: "a, b",
+		},
+		{
+			name: "natural and cross joins are left-deep",
+			sql:  "SELECT * FROM a NATURAL JOIN b CROSS JOIN c",
+			want: "a NATURAL INNER JOIN b CROSS JOIN c",
+		},
+		{
+			name: "schema-qualified table",
+			sql:  "SELECT * FROM main.users AS u FULL JOIN aux.orders ON uid = oid",
+			want: "main.users AS u FULL JOIN aux.orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			from := stmt.(*SelectStatement).From
+			if from == nil {
+				t.Fatal("Expected FROM clause")
+			}
+
+			if from.String() != tt.want {
+				t.Fatalf("Expected '%s', got '%s'", tt.want, from.String())
+			}
+		})
+	}
+}
+
+func TestParseJoinConstraints(t *testing.T) {
+	stmt, err := Parse("SELECT * FROM a LEFT JOIN b ON x = y JOIN c USING (k1, k2)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	outer, ok := stmt.(*SelectStatement).From.(*JoinExpr)
+	if !ok {
+		t.Fatalf("Expected JoinExpr, got %T", stmt.(*SelectStatement).From)
+	}
+
+	if len(outer.Using) != 2 {
+		t.Fatalf("Expected 2 USING columns, got %d", len(outer.Using))
+	}
+
+	inner, ok := outer.Left.(*JoinExpr)
+	if !ok {
+		t.Fatalf("Expected nested JoinExpr, got %T", outer.Left)
+	}
+
+	if inner.Type != "LEFT" || inner.On == nil {
+		t.Fatalf("Expected LEFT join with ON, got %s join", inner.Type)
+	}
+
+	if _, err := Parse("SELECT * FROM a NATURAL JOIN b ON x = y"); err == nil {
+		t.Fatal("Expected error for NATURAL join with ON clause")
+	}
+}
+
+func TestParseGroupByHaving(t *testing.T) {
+	sql := "SELECT dept, COUNT(id) FROM emp WHERE active = 1 GROUP BY dept, region HAVING COUNT(id) > 5 ORDER BY dept"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+
+	if len(selectStmt.GroupBy) != 2 {
+		t.Fatalf("Expected 2 GROUP BY expressions, got %d", len(selectStmt.GroupBy))
+	}
+
+	if selectStmt.Having == nil {
+		t.Fatal("Expected HAVING clause")
+	}
+
+	if len(selectStmt.OrderBy) != 1 {
+		t.Fatalf("Expected ORDER BY after HAVING, got %d items", len(selectStmt.OrderBy))
+	}
+
+	if _, err := Parse("SELECT COUNT(id) FROM emp HAVING COUNT(id) > 5"); err == nil {
+		t.Fatal("Expected error for HAVING without GROUP BY")
+	}
+}
+
+func TestParseCompoundSelect(t *testing.T) {
+	sql := "SELECT a FROM t1 UNION ALL SELECT b FROM t2 EXCEPT SELECT c FROM t3 ORDER BY 1 LIMIT 10"
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	compound, ok := stmt.(*CompoundSelect)
+	if !ok {
+		t.Fatalf("Expected CompoundSelect, got %T", stmt)
+	}
+
+	if len(compound.Selects) != 3 {
+		t.Fatalf("Expected 3 SELECT cores, got %d", len(compound.Selects))
+	}
+
+	want := []CompoundOperator{{Type: "UNION", All: true}, {Type: "EXCEPT"}}
+	for i, op := range want {
+		if compound.Operators[i] != op {
+			t.Fatalf("Operators[%d]: expected %s, got %s", i, op, compound.Operators[i])
+		}
+	}
+
+	if len(compound.OrderBy) != 1 || compound.Limit == nil {
+		t.Fatal("Expected ORDER BY and LIMIT on the compound")
+	}
+
+	if compound.Selects[2].OrderBy != nil || compound.Selects[2].Limit != nil {
+		t.Fatal("Expected last core to have no ORDER BY or LIMIT of its own")
+	}
+}
+
+func TestParseCompoundSelectErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "missing select after union",
+			sql:  "SELECT a FROM t1 UNION",
+		},
+		{
+			name: "all after intersect",
+			sql:  "SELECT a FROM t1 INTERSECT ALL SELECT b FROM t2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sql)
+			if err == nil {
+				t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseSubqueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		sql   string
+		check func(Expression) bool
+	}{
+		{
+			name: "in value list",
+			sql:  "SELECT * FROM t WHERE id IN (1, 2, 3)",
+			check: func(e Expression) bool {
+				in, ok := e.(*InExpression)
+				return ok && !in.Not && len(in.Values) == 3
+			},
+		},
+		{
+			name: "not in subquery",
+			sql:  "SELECT * FROM t WHERE id NOT IN (SELECT uid FROM banned)",
+			check: func(e Expression) bool {
+				in, ok := e.(*InExpression)
+				return ok && in.Not && in.Query != nil
+			},
+		},
+		{
+			name: "exists",
+			sql:  "SELECT * FROM t WHERE EXISTS (SELECT * FROM u WHERE uid = id)",
+			check: func(e Expression) bool {
+				_, ok := e.(*ExistsExpression)
+				return ok
+			},
+		},
+		{
+			name: "not exists",
+			sql:  "SELECT * FROM t WHERE NOT EXISTS (SELECT * FROM u)",
+			check: func(e Expression) bool {
+				not, ok := e.(*UnaryExpression)
+				if !ok {
+					return false
+				}
+				_, ok = not.Operand.(*ExistsExpression)
+				return ok
+			},
+		},
+		{
+			name: "scalar subquery in comparison",
+			sql:  "SELECT * FROM t WHERE price > (SELECT AVG(price) FROM t) AND id IN ()",
+			check: func(e Expression) bool {
+				and, ok := e.(*BinaryExpression)
+				if !ok {
+					return false
+				}
+				cmp, ok := and.Left.(*BinaryExpression)
+				if !ok {
+					return false
+				}
+				_, ok = cmp.Right.(*SubqueryExpression)
+				return ok
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			where := stmt.(*SelectStatement).Where
+			if !tt.check(where) {
+				t.Fatalf("Unexpected WHERE expression %T: %s", where, where)
+			}
+		})
+	}
+}
+
+func TestParseDerivedTables(t *testing.T) {
+	stmt, err := Parse("SELECT * FROM (SELECT id FROM users UNION SELECT id FROM admins) AS ids JOIN (a JOIN b) USING (id)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	join, ok := stmt.(*SelectStatement).From.(*JoinExpr)
+	if !ok {
+		t.Fatalf("Expected JoinExpr, got %T", stmt.(*SelectStatement).From)
+	}
+
+	derived, ok := join.Left.(*DerivedTable)
+	if !ok {
+		t.Fatalf("Expected DerivedTable, got %T", join.Left)
+	}
+
+	if derived.Alias == nil || derived.Alias.Name != "ids" {
+		t.Fatal("Expected derived table alias 'ids'")
+	}
+
+	if _, ok := derived.Query.(*CompoundSelect); !ok {
+		t.Fatalf("Expected compound query in derived table, got %T", derived.Query)
+	}
+
+	if _, ok := join.Right.(*ParenTableExpr); !ok {
+		t.Fatalf("Expected ParenTableExpr, got %T", join.Right)
+	}
+}
+
+func TestParseWithClause(t *testing.T) {
+	sql := `WITH RECURSIVE cnt(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM cnt WHERE x < 10),
+		big AS NOT MATERIALIZED (SELECT * FROM orders WHERE total > 100)
+		SELECT x FROM cnt`
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt, ok := stmt.(*SelectStatement)
+	if !ok {
+		t.Fatalf("Expected SelectStatement, got %T", stmt)
+	}
+
+	with := selectStmt.With
+	if with == nil || !with.Recursive {
+		t.Fatal("Expected WITH RECURSIVE clause")
+	}
+
+	if len(with.CTEs) != 2 {
+		t.Fatalf("Expected 2 CTEs, got %d", len(with.CTEs))
+	}
+
+	cnt := with.CTEs[0]
+	if cnt.Name.Name != "cnt" || len(cnt.Columns) != 1 {
+		t.Fatalf("Expected CTE cnt(x), got %s with %d columns", cnt.Name, len(cnt.Columns))
+	}
+
+	if _, ok := cnt.Query.(*CompoundSelect); !ok {
+		t.Fatalf("Expected compound CTE body, got %T", cnt.Query)
+	}
+
+	if with.CTEs[1].Materialized != "NOT MATERIALIZED" {
+		t.Fatalf("Expected NOT MATERIALIZED hint, got '%s'", with.CTEs[1].Materialized)
+	}
+}
+
+func TestParseWithStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		with func(Statement) *WithClause
+	}{
+		{
+			name: "insert",
+			sql:  "WITH src AS (SELECT * FROM staging) INSERT INTO users SELECT * FROM src",
+			with: func(s Statement) *WithClause { return s.(*InsertStatement).With },
+		},
+		{
+			name: "update",
+			sql:  "WITH ids AS MATERIALIZED (SELECT id FROM banned) UPDATE users SET active = 0 WHERE id IN (SELECT id FROM ids)",
+			with: func(s Statement) *WithClause { return s.(*UpdateStatement).With },
+		},
+		{
+			name: "delete",
+			sql:  "WITH old AS (SELECT id FROM logs) DELETE FROM logs WHERE id IN (SELECT id FROM old)",
+			with: func(s Statement) *WithClause { return s.(*DeleteStatement).With },
+		},
+		{
+			name: "compound select",
+			sql:  "WITH a AS (SELECT 1) SELECT * FROM a UNION SELECT * FROM a",
+			with: func(s Statement) *WithClause { return s.(*CompoundSelect).With },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if tt.with(stmt) == nil {
+				t.Fatalf("Expected WITH clause on %T", stmt)
+			}
+		})
+	}
+
+	if _, err := Parse("WITH a AS (SELECT 1) CREATE TABLE t (id INTEGER)"); err == nil {
+		t.Fatal("Expected error for WITH before CREATE")
+	}
+}
+
+func TestParseWindowFunctions(t *testing.T) {
+	sql := `SELECT ROW_NUMBER() OVER (PARTITION BY category ORDER BY price DESC),
+		SUM(qty) FILTER (WHERE qty > 0) OVER (w ROWS BETWEEN UNBOUNDED PRECEDING AND 1 FOLLOWING EXCLUDE CURRENT ROW),
+		AVG(price) OVER w
+		FROM products
+		WINDOW w AS (PARTITION BY category GROUPS 2 PRECEDING)`
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+	if len(selectStmt.Fields) != 3 {
+		t.Fatalf("Expected 3 fields, got %d", len(selectStmt.Fields))
+	}
+
+	rowNumber := selectStmt.Fields[0].(*FunctionCall)
+	if rowNumber.Over == nil || len(rowNumber.Over.PartitionBy) != 1 || len(rowNumber.Over.OrderBy) != 1 {
+		t.Fatal("Expected ROW_NUMBER() window with PARTITION BY and ORDER BY")
+	}
+
+	sum := selectStmt.Fields[1].(*FunctionCall)
+	if sum.Filter == nil {
+		t.Fatal("Expected FILTER clause on SUM")
+	}
+
+	frame := sum.Over.Frame
+	if sum.Over.Name == nil || sum.Over.Name.Name != "w" {
+		t.Fatal("Expected window to extend base window w")
+	}
+	if frame == nil || frame.Type != "ROWS" {
+		t.Fatal("Expected ROWS frame")
+	}
+	if frame.Start.Type != "UNBOUNDED PRECEDING" || frame.End.Type != "FOLLOWING" || frame.End.Offset == nil {
+		t.Fatalf("Unexpected frame bounds: %s .. %s", frame.Start.Type, frame.End.Type)
+	}
+	if frame.Exclude != "CURRENT ROW" {
+		t.Fatalf("Expected EXCLUDE CURRENT ROW, got '%s'", frame.Exclude)
+	}
+
+	avg := selectStmt.Fields[2].(*FunctionCall)
+	if avg.Over == nil || avg.Over.Name.Name != "w" {
+		t.Fatal("Expected OVER w on AVG")
+	}
+
+	if len(selectStmt.Windows) != 1 || selectStmt.Windows[0].Spec.Frame.Type != "GROUPS" {
+		t.Fatal("Expected WINDOW clause defining w with a GROUPS frame")
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "unterminated window",
+			sql:  "SELECT SUM(x) OVER (PARTITION BY y FROM t",
+		},
+		{
+			name: "bad frame bound",
+			sql:  "SELECT SUM(x) OVER (ROWS UNBOUNDED) FROM t",
+		},
+		{
+			name: "filter without where",
+			sql:  "SELECT SUM(x) FILTER (x > 1) FROM t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sql)
+			if err == nil {
+				t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseCaseExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		operand bool
+		whens   int
+		hasElse bool
+	}{
+		{
+			name:    "simple case",
+			sql:     "SELECT CASE status WHEN 1 THEN 'active' WHEN 2 THEN 'banned' END FROM users",
+			operand: true,
+			whens:   2,
+		},
+		{
+			name:    "searched case with else",
+			sql:     "SELECT CASE WHEN age < 18 THEN 'minor' WHEN age < 65 THEN 'adult' ELSE 'senior' END FROM users",
+			whens:   2,
+			hasElse: true,
+		},
+		{
+			name:    "nested case",
+			sql:     "SELECT CASE WHEN a = 1 THEN CASE b WHEN 2 THEN 'x' END ELSE 'y' END FROM t",
+			whens:   1,
+			hasElse: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			caseExpr, ok := stmt.(*SelectStatement).Fields[0].(*CaseExpression)
+			if !ok {
+				t.Fatalf("Expected CaseExpression, got %T", stmt.(*SelectStatement).Fields[0])
+			}
+
+			if (caseExpr.Operand != nil) != tt.operand {
+				t.Fatalf("Expected operand presence %t", tt.operand)
+			}
+
+			if len(caseExpr.Whens) != tt.whens {
+				t.Fatalf("Expected %d WHEN clauses, got %d", tt.whens, len(caseExpr.Whens))
+			}
+
+			if (caseExpr.Else != nil) != tt.hasElse {
+				t.Fatalf("Expected ELSE presence %t", tt.hasElse)
+			}
+		})
+	}
+
+	for _, sql := range []string{
+		"SELECT CASE END FROM t",
+		"SELECT CASE WHEN a THEN 1 FROM t",
+		"SELECT CASE WHEN a 1 END FROM t",
+	} {
+		if _, err := Parse(sql); err == nil {
+			t.Fatalf("Expected error for invalid SQL: %s", sql)
+		}
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	sql := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+;
+INSERT INTO users (name) VALUES ('a;b');
+-- trailing comment
+SELECT * FROM users`
+
+	stmts, err := ParseScript(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(stmts) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(stmts))
+	}
+	if _, ok := stmts[0].(*CreateTableStatement); !ok {
+		t.Fatalf("Expected CreateTableStatement, got %T", stmts[0])
+	}
+	if _, ok := stmts[1].(*InsertStatement); !ok {
+		t.Fatalf("Expected InsertStatement, got %T", stmts[1])
+	}
+	if _, ok := stmts[2].(*SelectStatement); !ok {
+		t.Fatalf("Expected SelectStatement, got %T", stmts[2])
+	}
+}
+
+func TestParserNextSpans(t *testing.T) {
+	sql := "BEGIN; ;; DELETE FROM t WHERE id = 1 ;COMMIT"
+	parser := NewParser(NewLexer(sql))
+
+	expected := []string{"BEGIN", "DELETE FROM t WHERE id = 1", "COMMIT"}
+	for i, want := range expected {
+		_, err := parser.Next()
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		span := parser.Span()
+		if got := sql[span.Start:span.End]; got != want {
+			t.Fatalf("statement %d: expected source %q, got %q", i, want, got)
+		}
+	}
+
+	if _, err := parser.Next(); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "missing semicolon",
+			sql:  "SELECT 1 SELECT 2",
+		},
+		{
+			name: "invalid second statement",
+			sql:  "SELECT 1; SELECT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScript(tt.sql)
+			if err == nil {
+				t.Fatalf("Expected error for invalid SQL: %s", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseScriptRecovery(t *testing.T) {
+	sql := "SELECT a, FROM t; DELET FROM x WHERE id = 1; SELECT 1 2; DROP TABLE y"
+
+	stmts, err := ParseScript(sql, WithErrorRecovery())
+	if err == nil {
+		t.Fatalf("Expected errors for invalid SQL: %s", sql)
+	}
+
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 3 {
+		t.Fatalf("Expected 3 errors, got %v", err)
+	}
+
+	if len(stmts) != 4 {
+		t.Fatalf("Expected 4 statements, got %d", len(stmts))
+	}
+
+	sel, ok := stmts[0].(*SelectStatement)
+	if !ok {
+		t.Fatalf("Expected SelectStatement, got %T", stmts[0])
+	}
+	if _, ok := sel.Fields[1].(*BadExpression); !ok {
+		t.Fatalf("Expected BadExpression, got %T", sel.Fields[1])
+	}
+	if sel.From == nil {
+		t.Fatalf("Expected FROM clause to be parsed after the bad expression")
+	}
+
+	if _, ok := stmts[1].(*BadStatement); !ok {
+		t.Fatalf("Expected BadStatement, got %T", stmts[1])
+	}
+	if _, ok := stmts[2].(*SelectStatement); !ok {
+		t.Fatalf("Expected SelectStatement, got %T", stmts[2])
+	}
+	if _, ok := stmts[3].(*DropStatement); !ok {
+		t.Fatalf("Expected DropStatement, got %T", stmts[3])
+	}
+
+	if _, err := ParseScript(sql); err == nil {
+		t.Fatalf("Expected error without recovery")
+	}
+}
+
+func TestParseRecovery(t *testing.T) {
+	stmt, err := Parse("SELECT FROM t", WithErrorRecovery())
+
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 1 {
+		t.Fatalf("Expected 1 error, got %v", err)
+	}
+
+	sel, ok := stmt.(*SelectStatement)
+	if !ok {
+		t.Fatalf("Expected SelectStatement, got %T", stmt)
+	}
+	if _, ok := sel.Fields[0].(*BadExpression); !ok {
+		t.Fatalf("Expected BadExpression, got %T", sel.Fields[0])
+	}
+
+	sql := "SELECT a, ] ] FROM t"
+	stmt, err = Parse(sql, WithErrorRecovery())
+	if !errors.As(err, &list) || len(list) != 1 {
+		t.Fatalf("Expected 1 error, got %v", err)
+	}
+	bad, ok := stmt.(*SelectStatement).Fields[1].(*BadExpression)
+	if !ok {
+		t.Fatalf("Expected BadExpression, got %T", stmt.(*SelectStatement).Fields[1])
+	}
+	if from, to := int(bad.From)-1, int(bad.To)-1; sql[from:to] != "] ]" {
+		t.Fatalf("Expected BadExpression to cover '] ]', got %q", sql[from:to])
+	}
+
+	sql = "SELECT a FROM"
+	stmt, err = Parse(sql, WithErrorRecovery())
+	if !errors.As(err, &list) || len(list) != 1 {
+		t.Fatalf("Expected 1 error, got %v", err)
+	}
+	badStmt, ok := stmt.(*BadStatement)
+	if !ok {
+		t.Fatalf("Expected BadStatement, got %T", stmt)
+	}
+	if from, to := int(badStmt.From)-1, int(badStmt.To)-1; sql[from:to] != sql {
+		t.Fatalf("Expected BadStatement to cover the input, got %q", sql[from:to])
+	}
+
+	if _, err := Parse("SELECT 1", WithErrorRecovery()); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	sql := "SELECT *\nFROM WHERE id = 1"
+	parser := NewParser(NewLexer(sql))
+
+	_, err := parser.ParseStatement()
+	if err == nil {
+		t.Fatalf("Expected error for invalid SQL: %s", sql)
+	}
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected *ParseError, got %T", err)
+	}
+	if perr.Line != 2 || perr.Col != 6 || perr.Offset != 14 {
+		t.Fatalf("Expected error at 2:6, offset 14, got %d:%d, offset %d", perr.Line, perr.Col, perr.Offset)
+	}
+	if perr.Token.Type != WHERE {
+		t.Fatalf("Expected offending token WHERE, got %s", perr.Token.Type)
+	}
+	if len(perr.Expected) != 1 || perr.Expected[0] != IDENTIFIER {
+		t.Fatalf("Expected IDENTIFIER in expected set, got %v", perr.Expected)
+	}
+
+	list := parser.Errors()
+	if len(list) != 1 || list[0] != perr {
+		t.Fatalf("Expected the error to be recorded, got %v", list)
+	}
+	if !errors.As(list.Err(), &perr) {
+		t.Fatalf("Expected ErrorList to unwrap to *ParseError")
+	}
+}
+
+func TestParseErrorFromLexer(t *testing.T) {
+	_, err := Parse("SELECT * FROM t WHERE name = 'abc")
+	if err == nil {
+		t.Fatalf("Expected error for unterminated string")
+	}
+	if !strings.Contains(err.Error(), "unterminated string literal") {
+		t.Fatalf("Expected lexer diagnostic in error, got %q", err)
+	}
+}
+
+func TestParseUnterminatedComment(t *testing.T) {
+	for _, sql := range []string{"SELECT 1 /* x", "SELECT 1; /* x"} {
+		_, err := ParseScript(sql)
+		if err == nil {
+			t.Fatalf("Expected error for unterminated comment in %q", sql)
+		}
+		if !strings.Contains(err.Error(), "unterminated block comment") {
+			t.Fatalf("Expected lexer diagnostic in error, got %q", err)
+		}
+	}
+
+	if _, err := Parse("SELECT 1 /* x"); err == nil {
+		t.Fatalf("Expected error for unterminated comment")
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	var list ErrorList
+	if list.Err() != nil {
+		t.Fatalf("Expected nil error for empty list")
+	}
+
+	list.Add(&ParseError{Line: 2, Col: 3, Offset: 10, Msg: "second"})
+	list.Add(&ParseError{Line: 1, Col: 1, Offset: 0, Msg: "first"})
+	list.Sort()
+
+	if list[0].Msg != "first" || list[1].Msg != "second" {
+		t.Fatalf("Expected errors sorted by offset, got %v", list)
+	}
+	if got := list.Err().Error(); got != "line 1, column 1: first\nline 2, column 3: second" {
+		t.Fatalf("Unexpected error text: %q", got)
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	sql := "SELECT 1;\nSELECT name, price * 2\nFROM main.products\nWHERE id IN (1, 2)"
+
+	fset := token.NewFileSet()
+	parser := NewParser(NewLexer(sql), WithFileSet(fset, "query.sql"))
+
+	if _, err := parser.Next(); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	stmt, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	selectStmt := stmt.(*SelectStatement)
+
+	source := func(n Node) string {
+		return sql[fset.Position(n.Pos()).Offset:fset.Position(n.End()).Offset]
+	}
+
+	pos := fset.Position(selectStmt.Pos())
+	if pos.Filename != "query.sql" || pos.Line != 2 || pos.Column != 1 {
+		t.Fatalf("Expected query.sql:2:1, got %s", pos)
+	}
+
+	tests := []struct {
+		node Node
+		want string
+	}{
+		{selectStmt, sql[10:]},
+		{selectStmt.Fields[0], "name"},
+		{selectStmt.Fields[1], "price * 2"},
+		{selectStmt.From, "main.products"},
+		{selectStmt.Where, "id IN (1, 2)"},
+	}
+
+	for _, tt := range tests {
+		if got := source(tt.node); got != tt.want {
+			t.Errorf("%T: expected source %q, got %q", tt.node, tt.want, got)
+		}
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	sql := `-- migration: 001
+-- creates the users table
+CREATE TABLE users (id INTEGER); -- up
+
+-- unattached
+
+/* backfill */
+INSERT INTO users (id) VALUES (1);
+DROP TABLE old`
+
+	parser := NewParser(NewLexer(sql, WithComments()))
+
+	var stmts []Statement
+	for {
+		stmt, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	if len(stmts) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(stmts))
+	}
+
+	tests := []struct {
+		stmt Statement
+		want []string
+	}{
+		{stmts[0], []string{"migration: 001\ncreates the users table", "up"}},
+		{stmts[1], []string{"backfill"}},
+		{stmts[2], nil},
+	}
+
+	comments := parser.CommentMap()
+	for _, tt := range tests {
+		groups := comments[tt.stmt]
+		if len(groups) != len(tt.want) {
+			t.Fatalf("%s: expected %d comment groups, got %d", tt.stmt, len(tt.want), len(groups))
+		}
+		for i, group := range groups {
+			if group.Text() != tt.want[i] {
+				t.Fatalf("%s: expected comment %q, got %q", tt.stmt, tt.want[i], group.Text())
+			}
+		}
+	}
+
+	if len(parser.Comments()) != 4 {
+		t.Fatalf("Expected 4 comment groups, got %d", len(parser.Comments()))
+	}
+}
+
+func TestParseInnerComments(t *testing.T) {
+	cmap := CommentMap{}
+	stmt, err := Parse("SELECT a /* inner */ FROM t WHERE id IN (SELECT id /* sub */ FROM u) -- last", WithCommentMap(cmap))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+	in := selectStmt.Where.(*InExpression)
+	subquery := in.Query
+
+	tests := []struct {
+		node Node
+		want []string
+	}{
+		{selectStmt, []string{"inner", "last"}},
+		{subquery, []string{"sub"}},
+	}
+	for _, tt := range tests {
+		groups := cmap[tt.node]
+		if len(groups) != len(tt.want) {
+			t.Fatalf("%s: expected %d comment groups, got %d", tt.node, len(tt.want), len(groups))
+		}
+		for i, group := range groups {
+			if group.Text() != tt.want[i] {
+				t.Fatalf("%s: expected comment %q, got %q", tt.node, tt.want[i], group.Text())
+			}
+		}
+	}
+
+	stmts, err := ParseScript("CREATE TABLE t (id INTEGER CHECK (id /* positive */ > 0));\n-- done\nDROP TABLE t", WithCommentMap(cmap))
+	if err != nil {
+		t.Fatalf("ParseScript failed: %v", err)
+	}
+	check := stmts[0].(*CreateTableStatement).Columns[0].Constraints[0].(*CheckConstraint)
+	if groups := cmap[check.Expr]; len(groups) != 1 || groups[0].Text() != "positive" {
+		t.Errorf("Expected comment positive on %s, got %v", check.Expr, groups)
+	}
+	if groups := cmap[stmts[1]]; len(groups) != 1 || groups[0].Text() != "done" {
+		t.Errorf("Expected comment done on %s, got %v", stmts[1], groups)
+	}
+}
+
+func TestParseDialects(t *testing.T) {
+	stmt, err := Parse("SELECT a::numeric(10,2), b->>'k' FROM t WHERE name NOT ILIKE $1 HAVING COUNT(*) > 1", WithDialect(PostgreSQL))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt := stmt.(*SelectStatement)
+	cast, ok := selectStmt.Fields[0].(*CastExpression)
+	if !ok {
+		t.Fatalf("Expected CastExpression, got %T", selectStmt.Fields[0])
+	}
+	if cast.String() != "a::numeric(10, 2)" {
+		t.Errorf("Expected a::numeric(10, 2), got %s", cast.String())
+	}
+
+	arrow, ok := selectStmt.Fields[1].(*BinaryExpression)
+	if !ok || arrow.Operator != "->>" {
+		t.Fatalf("Expected ->> expression, got %s", selectStmt.Fields[1])
+	}
+
+	where, ok := selectStmt.Where.(*BinaryExpression)
+	if !ok || where.Operator != "NOT ILIKE" {
+		t.Fatalf("Expected NOT ILIKE expression, got %s", selectStmt.Where)
+	}
+	if param, ok := where.Right.(*Parameter); !ok || param.Index != 1 {
+		t.Errorf("Expected parameter $1 with index 1, got %s", where.Right)
+	}
+
+	if selectStmt.Having == nil {
+		t.Fatal("Expected HAVING clause")
+	}
+
+	// The parser follows the dialect of its lexer.
+	parser := NewParser(NewLexer(`SELECT * FROM t WHERE s = "x" LIMIT 10, 20`, WithLexerDialect(MySQL)))
+	stmt, err = parser.ParseStatement()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	selectStmt = stmt.(*SelectStatement)
+	if _, ok := selectStmt.Where.(*BinaryExpression).Right.(*StringLiteral); !ok {
+		t.Errorf("Expected double-quoted string literal, got %T", selectStmt.Where.(*BinaryExpression).Right)
+	}
+	if selectStmt.Limit.Offset.String() != "10" || selectStmt.Limit.Count.String() != "20" {
+		t.Errorf("Expected LIMIT 20 OFFSET 10, got LIMIT %s OFFSET %s", selectStmt.Limit.Count, selectStmt.Limit.Offset)
+	}
+
+	stmts, err := ParseScript("PRAGMA foreign_keys = ON; SELECT [a] FROM t", WithDialect(SQLite))
+	if err != nil {
+		t.Fatalf("ParseScript failed: %v", err)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(stmts))
+	}
+}
+
+func TestParseDialectErrors(t *testing.T) {
+	tests := []struct {
+		dialect *Dialect
+		sql     string
+	}{
+		{SQLite, "SELECT a::int FROM t"},
+		{SQLite, "SELECT * FROM t WHERE a ILIKE 'x'"},
+		{SQLite, "SELECT COUNT(*) FROM t HAVING COUNT(*) > 1"},
+		{PostgreSQL, "SELECT * FROM t LIMIT 10, 20"},
+		{PostgreSQL, "PRAGMA foreign_keys = ON"},
+		{MySQL, "SELECT a::int FROM t"},
+		{MySQL, "SELECT * FROM t WHERE a = $1"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse(tt.sql, WithDialect(tt.dialect)); err == nil {
+			t.Errorf("%s: expected error for %q", tt.dialect, tt.sql)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{
+			name: "invalid token",
+			sql:  "INVALID STATEMENT",
+		},
+		{
+			name: "incomplete select",
+			sql:  "SELECT",
+		},
+		{
+			name: "missing table name",
+			sql:  "SELECT * FROM",
+		},
+		{
+			name: "trailing statement",
+			sql:  "SELECT 1; SELECT 2",
+		},
+		{
+			name: "unterminated string",
+			sql:  "SELECT 'abc",
+		},
+		{
+			name: "malformed number",
+			sql:  "SELECT 1e",
 		},
 	}
 